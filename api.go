@@ -3,6 +3,8 @@ package coinbasecommerce
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // Header keys that are required by the Coinbase Commerce API.
@@ -11,33 +13,193 @@ const (
 	APIHeaderVersion = "X-CC-Version"
 )
 
+// APIHeaderRequestID is the key of the response header that contains the ID
+// that the Coinbase Commerce API gave to a request.
+const APIHeaderRequestID = "X-Request-Id"
+
+// DefaultAPIBaseURL is the base URL of the Coinbase Commerce API.
+const DefaultAPIBaseURL = "https://api.commerce.coinbase.com"
+
 // APIConfig contains configuration that's needed by the Coinbase Commerce API.
 type APIConfig struct {
-	apiKey  string
-	version string
+	apiKey         string
+	version        string
+	baseURL        string
+	rateLimiter    *RateLimiter
+	circuitBreaker *CircuitBreaker
+	httpClient     *http.Client
+
+	keyProvider         KeyProvider
+	fallbackKeyProvider KeyProvider
 }
 
-// APIKey returns the API key from the API configuration object.
+// APIKey returns the API key from the API configuration object. The key that's
+// actually sent is provided by the key provider of the configuration.
 func (cfg *APIConfig) APIKey() string {
 	return cfg.apiKey
 }
 
+// KeyProvider returns the provider of the API key that's sent along with
+// every request; by default, it provides the API key of the configuration.
+func (cfg *APIConfig) KeyProvider() KeyProvider {
+	return cfg.keyProvider
+}
+
+// FallbackKeyProvider returns the provider of the API key that's sent when
+// the request with the key of the key provider has been rejected with an
+// authentication error; may be equal to nil, i.e. no fallback.
+func (cfg *APIConfig) FallbackKeyProvider() KeyProvider {
+	return cfg.fallbackKeyProvider
+}
+
 // Version returns the API version from the API configuration object.
 func (cfg *APIConfig) Version() string {
 	return cfg.version
 }
 
+// BaseURL returns the base URL of the API from the API configuration object.
+// It never ends with a slash.
+func (cfg *APIConfig) BaseURL() string {
+	return cfg.baseURL
+}
+
+// RateLimiter returns the rate limiter that's shared by the requests that use
+// the API configuration; may be equal to nil, i.e. no rate limiting.
+func (cfg *APIConfig) RateLimiter() *RateLimiter {
+	return cfg.rateLimiter
+}
+
+// CircuitBreaker returns the circuit breaker that's shared by the requests
+// that use the API configuration; may be equal to nil, i.e. no circuit
+// breaking.
+func (cfg *APIConfig) CircuitBreaker() *CircuitBreaker {
+	return cfg.circuitBreaker
+}
+
+// HTTPClient returns the HTTP client that's used by the API call contexts of
+// the API configuration by default; may be equal to nil, i.e.
+// http.DefaultClient.
+func (cfg *APIConfig) HTTPClient() *http.Client {
+	return cfg.httpClient
+}
+
+// APIConfigOptions contains options for the API configuration.
+type APIConfigOptions struct {
+	baseURL        string
+	rateLimiter    *RateLimiter
+	circuitBreaker *CircuitBreaker
+	httpClient     *http.Client
+
+	keyProvider         KeyProvider
+	fallbackKeyProvider KeyProvider
+}
+
+// APIConfigOptionFunc represents a function that can modify the contents
+// of the APIConfigOptions.
+type APIConfigOptionFunc func(*APIConfigOptions)
+
+// APIConfigOptionBaseURL sets the base URL that will be used for every request
+// to the Coinbase Commerce API, e.g. the URL of a local stand-in server or of
+// an egress proxy. The base URL may contain a path prefix.
+func APIConfigOptionBaseURL(baseURL string) APIConfigOptionFunc {
+	if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		panic(`invalid api base url. valid value must be an absolute url`)
+	}
+	return func(options *APIConfigOptions) {
+		options.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// APIConfigOptionRateLimiter sets the rate limiter that will be shared by all
+// the requests that use the API configuration.
+func APIConfigOptionRateLimiter(rateLimiter *RateLimiter) APIConfigOptionFunc {
+	return func(options *APIConfigOptions) {
+		options.rateLimiter = rateLimiter
+	}
+}
+
+// APIConfigOptionCircuitBreaker sets the circuit breaker that will be shared
+// by all the requests that use the API configuration.
+func APIConfigOptionCircuitBreaker(circuitBreaker *CircuitBreaker) APIConfigOptionFunc {
+	return func(options *APIConfigOptions) {
+		options.circuitBreaker = circuitBreaker
+	}
+}
+
+// APIConfigOptionHTTPClient sets the HTTP client that's used by the API call
+// contexts of the API configuration, unless they're created with
+// APICallContextOptionHTTPClient.
+func APIConfigOptionHTTPClient(httpClient *http.Client) APIConfigOptionFunc {
+	return func(options *APIConfigOptions) {
+		options.httpClient = httpClient
+	}
+}
+
+// APIConfigOptionKeyProvider sets the provider of the API key that's sent
+// along with every request, instead of the API key of the configuration.
+func APIConfigOptionKeyProvider(keyProvider KeyProvider) APIConfigOptionFunc {
+	if keyProvider == nil {
+		panic("keyProvider cannot be equal to nil")
+	}
+	return func(options *APIConfigOptions) {
+		options.keyProvider = keyProvider
+	}
+}
+
+// APIConfigOptionFallbackKeyProvider sets the provider of the secondary API
+// key, which is sent once more when a request has been rejected with an
+// authentication error, e.g. while the primary key is being rotated.
+func APIConfigOptionFallbackKeyProvider(keyProvider KeyProvider) APIConfigOptionFunc {
+	return func(options *APIConfigOptions) {
+		options.fallbackKeyProvider = keyProvider
+	}
+}
+
 // NewAPIConfig creates a new API configuration.
-func NewAPIConfig(apiKey, version string) *APIConfig {
-	return &APIConfig{apiKey, version}
+func NewAPIConfig(
+	apiKey, version string,
+	optionFuncs ...APIConfigOptionFunc,
+) *APIConfig {
+	options := APIConfigOptions{
+		baseURL:        DefaultAPIBaseURL,
+		rateLimiter:    nil,
+		circuitBreaker: nil,
+		httpClient:     nil,
+
+		keyProvider:         StaticKeyProvider(apiKey),
+		fallbackKeyProvider: nil,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	return &APIConfig{
+		apiKey:         apiKey,
+		version:        version,
+		baseURL:        options.baseURL,
+		rateLimiter:    options.rateLimiter,
+		circuitBreaker: options.circuitBreaker,
+		httpClient:     options.httpClient,
+
+		keyProvider:         options.keyProvider,
+		fallbackKeyProvider: options.fallbackKeyProvider,
+	}
 }
 
 // APICallContext contains objects that will be used during the execution
 // of a request to the Coinbase Commerce API.
 type APICallContext struct {
-	apiConfig  *APIConfig
-	httpClient *http.Client
-	context    context.Context
+	apiConfig    *APIConfig
+	httpClient   *http.Client
+	context      context.Context
+	retryPolicy  *RetryPolicy
+	interceptors []APIInterceptor
+	tracer       Tracer
+
+	idempotencyStore     IdempotencyStore
+	idempotencyScanLimit int
+
+	responseMeta *ResponseMeta
 }
 
 // APIConfig returns the API configuration object that will be used to
@@ -58,10 +220,55 @@ func (acc *APICallContext) Context() context.Context {
 	return acc.context
 }
 
+// RetryPolicy returns the policy that will be used to retry the request to
+// the Coinbase Commerce API; may be equal to nil, i.e. no retries.
+func (acc *APICallContext) RetryPolicy() *RetryPolicy {
+	return acc.retryPolicy
+}
+
+// Interceptors returns the interceptors that the API call will be passed
+// through, in order.
+func (acc *APICallContext) Interceptors() []APIInterceptor {
+	return acc.interceptors
+}
+
+// Tracer returns the tracer that will start the spans of the API call; may be
+// equal to nil, i.e. no tracing.
+func (acc *APICallContext) Tracer() Tracer {
+	return acc.tracer
+}
+
+// IdempotencyStore returns the store that records the resources that were
+// created with an idempotency key; may be equal to nil.
+func (acc *APICallContext) IdempotencyStore() IdempotencyStore {
+	return acc.idempotencyStore
+}
+
+// IdempotencyScanLimit returns the number of the most recently created
+// resources that are scanned for a resource with the same idempotency key
+// before a resource is created; 0 if they're not scanned.
+func (acc *APICallContext) IdempotencyScanLimit() int {
+	return acc.idempotencyScanLimit
+}
+
+// ResponseMeta returns the object that will receive the response metadata of
+// the API call; may be equal to nil.
+func (acc *APICallContext) ResponseMeta() *ResponseMeta {
+	return acc.responseMeta
+}
+
 // APICallContextOptions contains options for the Create API call.
 type APICallContextOptions struct {
-	httpClient *http.Client
-	context    context.Context
+	httpClient   *http.Client
+	context      context.Context
+	retryPolicy  *RetryPolicy
+	interceptors []APIInterceptor
+	tracer       Tracer
+
+	idempotencyStore     IdempotencyStore
+	idempotencyScanLimit int
+
+	responseMeta *ResponseMeta
 }
 
 // APICallContextOptionFunc represents a function that can modify the contents
@@ -84,6 +291,65 @@ func APICallContextOptionContext(context context.Context) APICallContextOptionFu
 	}
 }
 
+// APICallContextOptionRetryPolicy sets the policy that will be used to retry
+// a failed request to the Coinbase Commerce API.
+func APICallContextOptionRetryPolicy(retryPolicy *RetryPolicy) APICallContextOptionFunc {
+	return func(options *APICallContextOptions) {
+		options.retryPolicy = retryPolicy
+	}
+}
+
+// APICallContextOptionInterceptors appends interceptors to the chain that the
+// API call will be passed through. The interceptors that are added first are
+// the outermost ones.
+func APICallContextOptionInterceptors(interceptors ...APIInterceptor) APICallContextOptionFunc {
+	return func(options *APICallContextOptions) {
+		options.interceptors = append(
+			options.interceptors[:len(options.interceptors):len(options.interceptors)],
+			interceptors...,
+		)
+	}
+}
+
+// APICallContextOptionTracer sets the tracer that will start the spans of the
+// API call.
+func APICallContextOptionTracer(tracer Tracer) APICallContextOptionFunc {
+	return func(options *APICallContextOptions) {
+		options.tracer = tracer
+	}
+}
+
+// APICallContextOptionIdempotencyStore sets the store that will record the
+// resources that are created with an idempotency key.
+func APICallContextOptionIdempotencyStore(store IdempotencyStore) APICallContextOptionFunc {
+	return func(options *APICallContextOptions) {
+		options.idempotencyStore = store
+	}
+}
+
+// APICallContextOptionIdempotencyScanLimit sets the number of the most
+// recently created resources that are scanned for a resource with the same
+// idempotency key before a resource is created. It's 25 by default; 0 turns
+// the scan off.
+func APICallContextOptionIdempotencyScanLimit(limit int) APICallContextOptionFunc {
+	if limit > 100 || limit < 0 {
+		panic(`invalid idempotency scan limit. valid values: 0 <= limit <= 100`)
+	}
+	return func(options *APICallContextOptions) {
+		options.idempotencyScanLimit = limit
+	}
+}
+
+// APICallContextOptionResponseMeta sets the object that will receive the
+// response metadata of the API call. Since the object is overwritten by
+// every call that's made with the option, it should be passed to a single
+// call, e.g. as a per-call option of a Client.
+func APICallContextOptionResponseMeta(responseMeta *ResponseMeta) APICallContextOptionFunc {
+	return func(options *APICallContextOptions) {
+		options.responseMeta = responseMeta
+	}
+}
+
 // NewAPICallContext creates a new API call context.
 func NewAPICallContext(
 	apiConfig *APIConfig,
@@ -93,17 +359,62 @@ func NewAPICallContext(
 		panic("apiConfig cannot be equal to nil")
 	}
 
+	httpClient := apiConfig.HTTPClient()
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	options := APICallContextOptions{
+		httpClient:   httpClient,
+		context:      nil,
+		retryPolicy:  nil,
+		interceptors: nil,
+		tracer:       nil,
+
+		idempotencyStore:     nil,
+		idempotencyScanLimit: 25,
+
+		responseMeta: nil,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+	return newAPICallContext(apiConfig, options)
+}
+
+// With creates a copy of the API call context whose options are modified by
+// the option functions, e.g. to make the API calls that an operation depends
+// on without retrying them.
+func (acc *APICallContext) With(optionFuncs ...APICallContextOptionFunc) APICallContext {
 	options := APICallContextOptions{
-		httpClient: http.DefaultClient,
-		context:    nil,
+		httpClient:   acc.httpClient,
+		context:      acc.context,
+		retryPolicy:  acc.retryPolicy,
+		interceptors: acc.interceptors,
+		tracer:       acc.tracer,
+
+		idempotencyStore:     acc.idempotencyStore,
+		idempotencyScanLimit: acc.idempotencyScanLimit,
+
+		responseMeta: acc.responseMeta,
 	}
 	for _, optionFunc := range optionFuncs {
 		optionFunc(&options)
 	}
+	return newAPICallContext(acc.apiConfig, options)
+}
 
+func newAPICallContext(apiConfig *APIConfig, options APICallContextOptions) APICallContext {
 	return APICallContext{
-		apiConfig:  apiConfig,
-		httpClient: options.httpClient,
-		context:    options.context,
+		apiConfig:    apiConfig,
+		httpClient:   options.httpClient,
+		context:      options.context,
+		retryPolicy:  options.retryPolicy,
+		interceptors: options.interceptors,
+		tracer:       options.tracer,
+
+		idempotencyStore:     options.idempotencyStore,
+		idempotencyScanLimit: options.idempotencyScanLimit,
+
+		responseMeta: options.responseMeta,
 	}
 }
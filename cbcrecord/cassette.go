@@ -0,0 +1,143 @@
+package cbcrecord
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded pair of an HTTP request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette contains the interactions that were recorded, in order.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette from a JSON file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, err
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a JSON file, creating its directory if needed.
+func (cassette *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// sensitiveHeaders are the headers whose values are never recorded.
+var sensitiveHeaders = []string{
+	coinbasecommerce.APIHeaderAPIKey,
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+func scrubHeader(header http.Header) http.Header {
+	scrubbed := header.Clone()
+	for _, key := range sensitiveHeaders {
+		if scrubbed.Get(key) != "" {
+			scrubbed.Set(key, coinbasecommerce.RedactedValue)
+		}
+	}
+	return scrubbed
+}
+
+// unscrubbedMetadataKeys are the keys of the metadata whose values are kept
+// even if the metadata is scrubbed, since the library looks them up. E.g. a
+// charge that was created with an idempotency key is found by its key, so it
+// must be found the same way when the cassette is replayed.
+var unscrubbedMetadataKeys = map[string]bool{
+	charges.IdempotencyKeyMetadataKey: true,
+}
+
+// scrubber scrubs the values of the fields of the JSON bodies.
+type scrubber struct {
+	// fields are the fields whose values are scrubbed anywhere.
+	fields map[string]bool
+	// metadata is whether the values in the `metadata` fields are scrubbed,
+	// while their keys are kept.
+	metadata bool
+}
+
+// scrubBody replaces the values of the scrubbed fields anywhere in a JSON
+// body. Bodies that aren't JSON are kept as they are.
+func (s scrubber) scrubBody(body []byte) string {
+	if len(body) == 0 || len(s.fields) == 0 && !s.metadata {
+		return string(body)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	scrubbed, err := json.Marshal(s.scrubValue(value, false))
+	if err != nil {
+		return string(body)
+	}
+	return string(scrubbed)
+}
+
+func (s scrubber) scrubValue(value interface{}, inMetadata bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch {
+			case s.fields[key]:
+				v[key] = coinbasecommerce.RedactedValue
+			case key == "metadata" && s.metadata:
+				v[key] = s.scrubValue(field, true)
+			case inMetadata && unscrubbedMetadataKeys[key]:
+			default:
+				v[key] = s.scrubValue(field, inMetadata)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.scrubValue(item, inMetadata)
+		}
+	case nil:
+	default:
+		if inMetadata {
+			return coinbasecommerce.RedactedValue
+		}
+	}
+	return value
+}
@@ -0,0 +1,303 @@
+// Package cbcrecord provides an http.RoundTripper that records the requests to
+// the Coinbase Commerce API and their responses to a cassette file, and that
+// replays them later, so that tests can run offline and deterministically.
+//
+// The API key and other credentials are never recorded, and the values of the
+// personal fields of the JSON bodies, e.g. "email", and of the metadata of the
+// charges and checkouts, which may identify the customers, are scrubbed:
+//
+//	recorder, err := cbcrecord.New("testdata/create_charge.json", cbcrecord.ModeAuto)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer recorder.Stop()
+//
+//	charge, err := charges.Create(
+//		coinbasecommerce.NewAPICallContext(apiConfig,
+//			coinbasecommerce.APICallContextOptionHTTPClient(recorder.HTTPClient()),
+//		),
+//		request,
+//	)
+package cbcrecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// Mode represents what a Recorder does with the requests.
+type Mode int
+
+// Mode constants.
+const (
+	// ModeReplay responds to the requests with the recorded interactions,
+	// without sending them.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records them, overwriting the
+	// cassette when the recorder is stopped.
+	ModeRecord
+	// ModeAuto replays the cassette if it exists, and records it otherwise.
+	ModeAuto
+)
+
+// ErrNoInteraction is returned when a request is replayed but none of the
+// remaining recorded interactions match it.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// DefaultScrubbedFields are the fields of the JSON bodies whose values are
+// scrubbed by default.
+var DefaultScrubbedFields = []string{"email", "customer_email", "customer_name"}
+
+// MatcherFunc reports whether a request matches a recorded request. The
+// request's body is passed separately, already scrubbed the same way as the
+// recorded body.
+type MatcherFunc func(request *http.Request, body string, recorded Request) bool
+
+// Recorder is an http.RoundTripper that records or replays the interactions
+// of a cassette. It's safe for concurrent use by multiple goroutines.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	scrubber  scrubber
+	matcher   MatcherFunc
+
+	mu       sync.Mutex
+	cassette *Cassette
+	replayed []bool
+}
+
+// OptionFunc represents a function that can modify the contents of the
+// Recorder before it's used.
+type OptionFunc func(*Recorder)
+
+// OptionTransport sets the transport that sends the requests while
+// recording. http.DefaultTransport is used by default.
+func OptionTransport(transport http.RoundTripper) OptionFunc {
+	return func(recorder *Recorder) {
+		recorder.transport = transport
+	}
+}
+
+// OptionScrubbedFields replaces the fields of the JSON bodies whose values
+// are scrubbed, which are DefaultScrubbedFields by default.
+func OptionScrubbedFields(fields ...string) OptionFunc {
+	return func(recorder *Recorder) {
+		recorder.scrubber.fields = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			recorder.scrubber.fields[field] = true
+		}
+	}
+}
+
+// OptionScrubMetadata sets whether the values in the `metadata` fields of the
+// JSON bodies are scrubbed, while their keys are kept. They're scrubbed by
+// default, since the metadata often identifies the customers and the
+// cassettes are usually committed. The idempotency keys of the charges are
+// never scrubbed, so that the idempotent creations can be replayed.
+func OptionScrubMetadata(scrubMetadata bool) OptionFunc {
+	return func(recorder *Recorder) {
+		recorder.scrubber.metadata = scrubMetadata
+	}
+}
+
+// OptionMatcher replaces how requests are matched with the recorded ones,
+// which is by method, path, query and body by default.
+func OptionMatcher(matcher MatcherFunc) OptionFunc {
+	return func(recorder *Recorder) {
+		recorder.matcher = matcher
+	}
+}
+
+// New creates a new recorder of the cassette at path. In replay mode, the
+// cassette is loaded right away.
+func New(path string, mode Mode, optionFuncs ...OptionFunc) (*Recorder, error) {
+	recorder := Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		scrubber:  scrubber{metadata: true},
+		matcher:   DefaultMatcher,
+	}
+	OptionScrubbedFields(DefaultScrubbedFields...)(&recorder)
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&recorder)
+	}
+
+	if recorder.mode == ModeAuto {
+		if _, err := os.Stat(path); err == nil {
+			recorder.mode = ModeReplay
+		} else if os.IsNotExist(err) {
+			recorder.mode = ModeRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	switch recorder.mode {
+	case ModeReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		recorder.cassette = cassette
+		recorder.replayed = make([]bool, len(cassette.Interactions))
+	case ModeRecord:
+		recorder.cassette = &Cassette{}
+	default:
+		return nil, fmt.Errorf("invalid mode: %d", mode)
+	}
+	return &recorder, nil
+}
+
+// Mode returns the mode of the recorder, which is either ModeReplay or
+// ModeRecord.
+func (recorder *Recorder) Mode() Mode {
+	return recorder.mode
+}
+
+// HTTPClient creates an HTTP client that uses the recorder as its transport.
+func (recorder *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: recorder}
+}
+
+// Stop saves the cassette if the recorder is recording.
+func (recorder *Recorder) Stop() error {
+	if recorder.mode != ModeRecord {
+		return nil
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return recorder.cassette.Save(recorder.path)
+}
+
+// RoundTrip records or replays the request.
+func (recorder *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body.Close()
+		request = request.Clone(request.Context())
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if recorder.mode == ModeReplay {
+		return recorder.replay(request, body)
+	}
+	return recorder.record(request, body)
+}
+
+func (recorder *Recorder) replay(request *http.Request, body []byte) (*http.Response, error) {
+	scrubbedBody := recorder.scrubber.scrubBody(body)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	for i, interaction := range recorder.cassette.Interactions {
+		if recorder.replayed[i] || !recorder.matcher(request, scrubbedBody, interaction.Request) {
+			continue
+		}
+		recorder.replayed[i] = true
+		return interaction.Response.httpResponse(request), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, request.Method, request.URL.RequestURI())
+}
+
+func (recorder *Recorder) record(request *http.Request, body []byte) (*http.Response, error) {
+	response, err := recorder.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: request.Method,
+			URL:    request.URL.String(),
+			Header: scrubHeader(request.Header),
+			Body:   recorder.scrubber.scrubBody(body),
+		},
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Header:     scrubHeader(response.Header),
+			Body:       recorder.scrubber.scrubBody(responseBody),
+		},
+	}
+
+	// the length of the scrubbed body may differ
+	interaction.Response.Header.Del("Content-Length")
+
+	recorder.mu.Lock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, interaction)
+	recorder.mu.Unlock()
+
+	return response, nil
+}
+
+func (response Response) httpResponse(request *http.Request) *http.Response {
+	header := response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(response.Body))),
+		ContentLength: int64(len(response.Body)),
+		Request:       request,
+	}
+}
+
+// DefaultMatcher matches the requests by method, path, query and body. The
+// host is ignored so that a cassette can be replayed against any base URL,
+// and JSON bodies are compared by their values rather than their encodings.
+func DefaultMatcher(request *http.Request, body string, recorded Request) bool {
+	if request.Method != recorded.Method {
+		return false
+	}
+
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if request.URL.EscapedPath() != recordedURL.EscapedPath() {
+		return false
+	}
+	if !reflect.DeepEqual(request.URL.Query(), recordedURL.Query()) {
+		return false
+	}
+	return bodiesEqual(body, recorded.Body)
+}
+
+func bodiesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var valueA, valueB interface{}
+	if json.Unmarshal([]byte(a), &valueA) != nil || json.Unmarshal([]byte(b), &valueB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
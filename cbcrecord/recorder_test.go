@@ -0,0 +1,246 @@
+package cbcrecord_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbcrecord"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+const (
+	testAPIKey       = "secret-api-key"
+	testRequestBody  = `{"name": "Order", "metadata": {"customer_id": "42"}, "customer_email": "a@example.com"}`
+	testResponseBody = `{"data": {"code": "ABC", "metadata": {"customer_id": "42"}, "customer_name": "Alice"}}`
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(testResponseBody))
+	}))
+	path, cleanup := tempCassettePath(t)
+	defer cleanup()
+
+	recorder, err := cbcrecord.New(path, cbcrecord.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Mode() != cbcrecord.ModeRecord {
+		t.Fatalf("Mode() = %d without a cassette, want ModeRecord", recorder.Mode())
+	}
+	response := do(t, recorder.HTTPClient(), server.URL+"/charges?limit=1&order=desc", testRequestBody)
+	if body := readBody(t, response); body != testResponseBody {
+		t.Errorf("recorded response body = %s, want the real one %s", body, testResponseBody)
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	cassette, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{testAPIKey, "a@example.com", "Alice", `"42"`, "session=secret"} {
+		if bytes.Contains(cassette, []byte(secret)) {
+			t.Errorf("cassette contains %q:\n%s", secret, cassette)
+		}
+	}
+	if !bytes.Contains(cassette, []byte("customer_id")) {
+		t.Errorf("cassette doesn't contain the metadata keys:\n%s", cassette)
+	}
+
+	// the server is closed, so the responses can only come from the cassette,
+	// and the host and the order of the query and body fields don't matter
+	recorder, err = cbcrecord.New(path, cbcrecord.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Mode() != cbcrecord.ModeReplay {
+		t.Fatalf("Mode() = %d with a cassette, want ModeReplay", recorder.Mode())
+	}
+	response = do(t, recorder.HTTPClient(), "http://replay.invalid/charges?order=desc&limit=1",
+		`{"customer_email": "b@example.com", "metadata": {"customer_id": "43"}, "name": "Order"}`)
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("replayed status code = %d, want %d", response.StatusCode, http.StatusCreated)
+	}
+	if body := readBody(t, response); !strings.Contains(body, `"code":"ABC"`) {
+		t.Errorf("replayed response body = %s, want the recorded one", body)
+	}
+
+	// every interaction is only replayed once
+	_, err = recorder.HTTPClient().Post("http://replay.invalid/charges?order=desc&limit=1",
+		"application/json", strings.NewReader(testRequestBody))
+	if !errors.Is(err, cbcrecord.ErrNoInteraction) {
+		t.Errorf("second replay = %v, want %v", err, cbcrecord.ErrNoInteraction)
+	}
+}
+
+func TestRecordWithoutMetadataScrubbing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testResponseBody))
+	}))
+	defer server.Close()
+	path, cleanup := tempCassettePath(t)
+	defer cleanup()
+
+	recorder, err := cbcrecord.New(path, cbcrecord.ModeRecord, cbcrecord.OptionScrubMetadata(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readBody(t, do(t, recorder.HTTPClient(), server.URL+"/charges", testRequestBody))
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := cbcrecord.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := cassette.Interactions[0].Request.Body; !strings.Contains(body, `"customer_id":"42"`) ||
+		strings.Contains(body, "a@example.com") {
+		t.Errorf("recorded request body = %s, want only the metadata to be kept", body)
+	}
+}
+
+func TestDefaultMatcher(t *testing.T) {
+	recorded := cbcrecord.Request{
+		Method: http.MethodPost,
+		URL:    "https://api.commerce.coinbase.com/charges?a=1&b=2",
+		Body:   `{"name":"Order","pricing_type":"no_price"}`,
+	}
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		want   bool
+	}{
+		{"same", http.MethodPost, "https://api.commerce.coinbase.com/charges?a=1&b=2",
+			`{"name":"Order","pricing_type":"no_price"}`, true},
+		{"other host and field order", http.MethodPost, "http://localhost:4242/charges?b=2&a=1",
+			`{"pricing_type": "no_price", "name": "Order"}`, true},
+		{"other method", http.MethodGet, "https://api.commerce.coinbase.com/charges?a=1&b=2",
+			`{"name":"Order","pricing_type":"no_price"}`, false},
+		{"other path", http.MethodPost, "https://api.commerce.coinbase.com/checkouts?a=1&b=2",
+			`{"name":"Order","pricing_type":"no_price"}`, false},
+		{"other query", http.MethodPost, "https://api.commerce.coinbase.com/charges?a=1",
+			`{"name":"Order","pricing_type":"no_price"}`, false},
+		{"other body", http.MethodPost, "https://api.commerce.coinbase.com/charges?a=1&b=2",
+			`{"name":"Other","pricing_type":"no_price"}`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if got := cbcrecord.DefaultMatcher(request, test.body, recorded); got != test.want {
+				t.Errorf("DefaultMatcher() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func do(t *testing.T, client *http.Client, url, body string) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(coinbasecommerce.APIHeaderAPIKey, testAPIKey)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func readBody(t *testing.T, response *http.Response) string {
+	t.Helper()
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// tempCassettePath returns the path of a cassette in a new temporary
+// directory, which is removed by the returned function.
+func tempCassettePath(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cbcrecord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "testdata", "cassette.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestRecordThenReplayIdempotentCreate(t *testing.T) {
+	server := cbctest.NewServer()
+	path, cleanup := tempCassettePath(t)
+	defer cleanup()
+
+	createTwice := func(mode cbcrecord.Mode, baseURL string) []coinbasecommerce.Charge {
+		t.Helper()
+		recorder, err := cbcrecord.New(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		apiCallContext := coinbasecommerce.NewAPICallContext(
+			coinbasecommerce.NewAPIConfig(cbctest.APIKey, cbctest.APIVersion,
+				coinbasecommerce.APIConfigOptionBaseURL(baseURL)),
+			coinbasecommerce.APICallContextOptionHTTPClient(recorder.HTTPClient()),
+		)
+		request := charges.CreateRequest{
+			Name:           "Order",
+			Description:    "An order",
+			PricingType:    coinbasecommerce.PricingTypeNone,
+			Metadata:       map[string]string{"customer_id": "42"},
+			IdempotencyKey: "order-42",
+		}
+		var created []coinbasecommerce.Charge
+		for i := 0; i < 2; i++ {
+			charge, _, err := charges.Create(apiCallContext, request)
+			if err != nil {
+				t.Fatalf("Create() #%d error = %v", i+1, err)
+			}
+			created = append(created, charge)
+		}
+		if err := recorder.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+
+	recorded := createTwice(cbcrecord.ModeRecord, server.URL())
+	server.Close()
+	if recorded[1].ID != recorded[0].ID {
+		t.Fatalf("recorded Create() charge IDs = %s and %s, want the same charge", recorded[0].ID, recorded[1].ID)
+	}
+	cassette, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cassette, []byte(`"42"`)) {
+		t.Errorf("cassette contains the metadata value %q:\n%s", "42", cassette)
+	}
+
+	replayed := createTwice(cbcrecord.ModeReplay, "http://replay.invalid")
+	for i := range replayed {
+		if replayed[i].ID != recorded[i].ID {
+			t.Errorf("replayed Create() #%d charge ID = %s, want %s", i+1, replayed[i].ID, recorded[i].ID)
+		}
+	}
+}
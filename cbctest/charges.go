@@ -0,0 +1,326 @@
+package cbctest
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// ChargeLifetime is how long a new charge can be paid, i.e. the time between
+// its creation and its expiration.
+const ChargeLifetime = time.Hour
+
+// cryptoRate is the fake price of a cryptocurrency in any local currency.
+type cryptoRate struct {
+	// key is the key of the cryptocurrency in the pricing of a charge, which
+	// is also the name of its network.
+	key      string
+	currency coinbasecommerce.Currency
+	rate     float64
+}
+
+var exchangeRates = []cryptoRate{
+	{"bitcoin", coinbasecommerce.CurrencyBitcoin, 50000},
+	{"bitcoincash", coinbasecommerce.CurrencyBitcoinCash, 500},
+	{"ethereum", coinbasecommerce.CurrencyEthereum, 3000},
+	{"litecoin", coinbasecommerce.CurrencyLitecoin, 150},
+	{"usdc", coinbasecommerce.CurrencyUSDCoin, 1},
+	{"dai", coinbasecommerce.CurrencyDai, 1},
+}
+
+// Pricing converts the local price to the prices in every cryptocurrency,
+// by their keys in the pricing of a charge, e.g. "bitcoin", at fake rates.
+func Pricing(localPrice coinbasecommerce.Money) map[string]coinbasecommerce.Money {
+	prices := map[string]coinbasecommerce.Money{"local": localPrice}
+	for _, exchangeRate := range exchangeRates {
+		prices[exchangeRate.key] = coinbasecommerce.Money{
+			Amount:   math.Round(localPrice.Amount/exchangeRate.rate*1e8) / 1e8,
+			Currency: exchangeRate.currency,
+		}
+	}
+	return prices
+}
+
+// Networks returns the networks that payments can be made on.
+func Networks() []string {
+	networks := make([]string, len(exchangeRates))
+	for i, rate := range exchangeRates {
+		networks[i] = rate.key
+	}
+	return networks
+}
+
+func exchangeRate(network string) (cryptoRate, bool) {
+	for _, rate := range exchangeRates {
+		if rate.key == network {
+			return rate, true
+		}
+	}
+	return cryptoRate{}, false
+}
+
+// chargeStatus returns the current status of the charge.
+func chargeStatus(charge *coinbasecommerce.Charge) coinbasecommerce.ChargeStatus {
+	if len(charge.Timeline) == 0 {
+		return coinbasecommerce.ChargeStatusNew
+	}
+	return charge.Timeline[len(charge.Timeline)-1].Status
+}
+
+func cloneCharge(charge *coinbasecommerce.Charge) coinbasecommerce.Charge {
+	clone := *charge
+	clone.Timeline = append([]coinbasecommerce.ChargeStatusUpdate(nil), charge.Timeline...)
+	clone.Payments = append([]interface{}(nil), charge.Payments...)
+	if charge.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(charge.Metadata))
+		for key, value := range charge.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	if charge.Checkout != nil {
+		clone.Checkout = make(map[string]string, len(charge.Checkout))
+		for key, value := range charge.Checkout {
+			clone.Checkout[key] = value
+		}
+	}
+	if charge.Pricing != nil {
+		clone.Pricing = make(map[string]coinbasecommerce.Money, len(charge.Pricing))
+		for key, value := range charge.Pricing {
+			clone.Pricing[key] = value
+		}
+	}
+	return clone
+}
+
+// AddCharge adds the charge to the fake API as it is, except that its ID,
+// code, resource, hosted URL, times and timeline are filled in if they're
+// empty, and its prices in every cryptocurrency if it only has a local one.
+// It returns the charge that was added.
+func (server *Server) AddCharge(charge coinbasecommerce.Charge) coinbasecommerce.Charge {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return cloneCharge(server.addCharge(charge))
+}
+
+func (server *Server) addCharge(charge coinbasecommerce.Charge) *coinbasecommerce.Charge {
+	charge = cloneCharge(&charge)
+	if charge.ID == "" {
+		charge.ID = NewID()
+	}
+	if charge.Code == "" {
+		charge.Code = NewCode()
+	}
+	if charge.Resource == "" {
+		charge.Resource = "charge"
+	}
+	if charge.HostedURL == "" {
+		charge.HostedURL = server.hostedURL + charge.Code
+	}
+	if charge.CreatedAt.IsZero() {
+		charge.CreatedAt = server.now()
+	}
+	if charge.ExpiresAt.IsZero() {
+		charge.ExpiresAt = charge.CreatedAt.Add(ChargeLifetime)
+	}
+	if len(charge.Timeline) == 0 {
+		charge.Timeline = []coinbasecommerce.ChargeStatusUpdate{
+			{Time: charge.CreatedAt, Status: coinbasecommerce.ChargeStatusNew},
+		}
+	}
+	if localPrice, ok := charge.Pricing["local"]; ok && len(charge.Pricing) == 1 {
+		charge.Pricing = Pricing(localPrice)
+	} else if charge.Pricing == nil {
+		charge.Pricing = map[string]coinbasecommerce.Money{}
+	}
+	if charge.Payments == nil {
+		charge.Payments = []interface{}{}
+	}
+
+	server.charges = append(server.charges, &charge)
+	return &charge
+}
+
+// Charge returns the charge with the ID or code, if there's any.
+func (server *Server) Charge(idOrCode string) (coinbasecommerce.Charge, bool) {
+	var events []coinbasecommerce.Event
+	defer func() { server.deliver(events) }()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.expireCharges(&events)
+	charge := server.findCharge(idOrCode)
+	if charge == nil {
+		return coinbasecommerce.Charge{}, false
+	}
+	return cloneCharge(charge), true
+}
+
+// Charges returns all the charges in the order that they were added.
+func (server *Server) Charges() []coinbasecommerce.Charge {
+	var events []coinbasecommerce.Event
+	defer func() { server.deliver(events) }()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.expireCharges(&events)
+
+	charges := make([]coinbasecommerce.Charge, len(server.charges))
+	for i, charge := range server.charges {
+		charges[i] = cloneCharge(charge)
+	}
+	return charges
+}
+
+// RedirectURLs returns the URLs that the customer is sent to after paying
+// for the charge with the ID or code, or after canceling it, if the charge
+// was created with them.
+func (server *Server) RedirectURLs(idOrCode string) (redirectURL, cancelURL string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if charge := server.findCharge(idOrCode); charge != nil {
+		urls := server.redirectURLs[charge.ID]
+		return urls[0], urls[1]
+	}
+	return "", ""
+}
+
+func (server *Server) findCharge(idOrCode string) *coinbasecommerce.Charge {
+	for _, charge := range server.charges {
+		if charge.ID == idOrCode || charge.Code == idOrCode {
+			return charge
+		}
+	}
+	return nil
+}
+
+type createChargeRequest struct {
+	Name        string                       `json:"name"`
+	Description string                       `json:"description"`
+	PricingType coinbasecommerce.PricingType `json:"pricing_type"`
+	LocalPrice  *coinbasecommerce.Money      `json:"local_price"`
+	Metadata    map[string]string            `json:"metadata"`
+	RedirectURL string                       `json:"redirect_url"`
+	CancelURL   string                       `json:"cancel_url"`
+}
+
+func (server *Server) createCharge(r *http.Request) response {
+	var request createChargeRequest
+	if resp := decodeBody(r, &request); resp != nil {
+		return *resp
+	}
+
+	var v validator
+	v.required("name", request.Name != "")
+	v.required("description", request.Description != "")
+	v.check("name", len(request.Name) <= 100, "is too long (maximum is 100 characters)")
+	v.check("description", len(request.Description) <= 200, "is too long (maximum is 200 characters)")
+	validatePricing(&v, request.PricingType, request.LocalPrice)
+	if resp := v.response(); resp != nil {
+		return *resp
+	}
+
+	charge := coinbasecommerce.Charge{
+		Name:        request.Name,
+		Description: request.Description,
+		Metadata:    request.Metadata,
+		PricingType: request.PricingType,
+	}
+	if request.PricingType == coinbasecommerce.PricingTypeFixed {
+		charge.Pricing = Pricing(*request.LocalPrice)
+	}
+
+	var events []coinbasecommerce.Event
+	defer func() { server.deliver(events) }()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	added := server.addCharge(charge)
+	if request.RedirectURL != "" || request.CancelURL != "" {
+		server.redirectURLs[added.ID] = [2]string{request.RedirectURL, request.CancelURL}
+	}
+	server.newEvent(coinbasecommerce.EventTypeChargeCreated, added, &events)
+
+	resp := dataResponse(cloneCharge(added))
+	resp.statusCode = http.StatusCreated
+	return resp
+}
+
+func (server *Server) getCharge(idOrCode string) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	charge := server.findCharge(idOrCode)
+	if charge == nil {
+		return notFoundResponse()
+	}
+	return dataResponse(cloneCharge(charge))
+}
+
+func (server *Server) listCharges(r *http.Request) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	ids := make([]string, len(server.charges))
+	for i, charge := range server.charges {
+		ids[i] = charge.ID
+	}
+	page, pagination, resp := paginate(r, ids)
+	if resp != nil {
+		return *resp
+	}
+
+	charges := make([]coinbasecommerce.Charge, len(page))
+	for i, index := range page {
+		charges[i] = cloneCharge(server.charges[index])
+	}
+	return response{
+		statusCode: http.StatusOK,
+		envelope:   envelope{Data: charges, Pagination: pagination},
+	}
+}
+
+func (server *Server) cancelCharge(idOrCode string) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	charge := server.findCharge(idOrCode)
+	if charge == nil {
+		return notFoundResponse()
+	}
+	if status := chargeStatus(charge); status != coinbasecommerce.ChargeStatusNew {
+		return errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeInvalidRequest,
+			"Only new charges can be canceled; the charge is "+string(status))
+	}
+	charge.Timeline = append(charge.Timeline, coinbasecommerce.ChargeStatusUpdate{
+		Time:   server.now(),
+		Status: coinbasecommerce.ChargeStatusCanceled,
+	})
+	return dataResponse(cloneCharge(charge))
+}
+
+func (server *Server) resolveCharge(idOrCode string) response {
+	var events []coinbasecommerce.Event
+	defer func() { server.deliver(events) }()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	charge := server.findCharge(idOrCode)
+	if charge == nil {
+		return notFoundResponse()
+	}
+	if status := chargeStatus(charge); status != coinbasecommerce.ChargeStatusUnresolved {
+		return errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeInvalidRequest,
+			"Only unresolved charges can be resolved; the charge is "+string(status))
+	}
+	server.setStatus(charge, coinbasecommerce.ChargeStatusResolved, "",
+		coinbasecommerce.EventTypeChargeResolved, &events)
+	return dataResponse(cloneCharge(charge))
+}
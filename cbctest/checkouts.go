@@ -0,0 +1,203 @@
+package cbctest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+func cloneCheckout(checkout *coinbasecommerce.Checkout) coinbasecommerce.Checkout {
+	clone := *checkout
+	if checkout.LocalPrice != nil {
+		localPrice := *checkout.LocalPrice
+		clone.LocalPrice = &localPrice
+	}
+	clone.RequestedInfo = append([]coinbasecommerce.RequestableInfo(nil), checkout.RequestedInfo...)
+	return clone
+}
+
+// AddCheckout adds the checkout to the fake API as it is, except that its ID
+// and resource are filled in if they're empty. It returns the checkout that
+// was added.
+func (server *Server) AddCheckout(checkout coinbasecommerce.Checkout) coinbasecommerce.Checkout {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return cloneCheckout(server.addCheckout(checkout))
+}
+
+func (server *Server) addCheckout(checkout coinbasecommerce.Checkout) *coinbasecommerce.Checkout {
+	checkout = cloneCheckout(&checkout)
+	if checkout.ID == "" {
+		checkout.ID = NewID()
+	}
+	if checkout.Resource == "" {
+		checkout.Resource = "checkout"
+	}
+
+	server.checkouts = append(server.checkouts, &checkout)
+	return &checkout
+}
+
+// Checkout returns the checkout with the ID, if there's any.
+func (server *Server) Checkout(id string) (coinbasecommerce.Checkout, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if i := server.findCheckout(id); i >= 0 {
+		return cloneCheckout(server.checkouts[i]), true
+	}
+	return coinbasecommerce.Checkout{}, false
+}
+
+// Checkouts returns all the checkouts in the order that they were added.
+func (server *Server) Checkouts() []coinbasecommerce.Checkout {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	checkouts := make([]coinbasecommerce.Checkout, len(server.checkouts))
+	for i, checkout := range server.checkouts {
+		checkouts[i] = cloneCheckout(checkout)
+	}
+	return checkouts
+}
+
+func (server *Server) findCheckout(id string) int {
+	for i, checkout := range server.checkouts {
+		if checkout.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func validateCheckout(v *validator, checkout *coinbasecommerce.Checkout) {
+	v.required("name", checkout.Name != "")
+	v.required("description", checkout.Description != "")
+	v.check("name", len(checkout.Name) <= 100, "is too long (maximum is 100 characters)")
+	v.check("description", len(checkout.Description) <= 200, "is too long (maximum is 200 characters)")
+	validatePricing(v, checkout.PricingType, checkout.LocalPrice)
+	for _, info := range checkout.RequestedInfo {
+		v.check("requested_info",
+			info == coinbasecommerce.RequestableInfoEmail || info == coinbasecommerce.RequestableInfoName,
+			"must only contain email, name")
+	}
+}
+
+func (server *Server) createCheckout(r *http.Request) response {
+	var checkout coinbasecommerce.Checkout
+	if resp := decodeBody(r, &checkout); resp != nil {
+		return *resp
+	}
+	checkout.ID, checkout.Resource = "", ""
+
+	var v validator
+	validateCheckout(&v, &checkout)
+	if resp := v.response(); resp != nil {
+		return *resp
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	resp := dataResponse(cloneCheckout(server.addCheckout(checkout)))
+	resp.statusCode = http.StatusCreated
+	return resp
+}
+
+func (server *Server) getCheckout(id string) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	i := server.findCheckout(id)
+	if i < 0 {
+		return notFoundResponse()
+	}
+	return dataResponse(cloneCheckout(server.checkouts[i]))
+}
+
+func (server *Server) listCheckouts(r *http.Request) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	ids := make([]string, len(server.checkouts))
+	for i, checkout := range server.checkouts {
+		ids[i] = checkout.ID
+	}
+	page, pagination, resp := paginate(r, ids)
+	if resp != nil {
+		return *resp
+	}
+
+	checkouts := make([]coinbasecommerce.Checkout, len(page))
+	for i, index := range page {
+		checkouts[i] = cloneCheckout(server.checkouts[index])
+	}
+	return response{
+		statusCode: http.StatusOK,
+		envelope:   envelope{Data: checkouts, Pagination: pagination},
+	}
+}
+
+func (server *Server) updateCheckout(r *http.Request, id string) response {
+	var fields map[string]json.RawMessage
+	if resp := decodeBody(r, &fields); resp != nil {
+		return *resp
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	i := server.findCheckout(id)
+	if i < 0 {
+		return notFoundResponse()
+	}
+
+	// only the fields that are in the request are changed
+	checkout := cloneCheckout(server.checkouts[i])
+	for field, value := range fields {
+		var target interface{}
+		switch field {
+		case "name":
+			target = &checkout.Name
+		case "description":
+			target = &checkout.Description
+		case "pricing_type":
+			target = &checkout.PricingType
+		case "local_price":
+			checkout.LocalPrice = nil
+			target = &checkout.LocalPrice
+		case "requested_info":
+			checkout.RequestedInfo = nil
+			target = &checkout.RequestedInfo
+		default:
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			return errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeInvalidRequest,
+				"Invalid "+field+": "+err.Error())
+		}
+	}
+
+	var v validator
+	validateCheckout(&v, &checkout)
+	if resp := v.response(); resp != nil {
+		return *resp
+	}
+
+	*server.checkouts[i] = checkout
+	return dataResponse(cloneCheckout(&checkout))
+}
+
+func (server *Server) deleteCheckout(id string) response {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	i := server.findCheckout(id)
+	if i < 0 {
+		return notFoundResponse()
+	}
+	server.checkouts = append(server.checkouts[:i], server.checkouts[i+1:]...)
+	return response{statusCode: http.StatusOK}
+}
@@ -0,0 +1,305 @@
+// Package factory builds realistic and internally consistent fixtures of
+// charges, checkouts, payments and webhook events, e.g. a completed charge
+// whose timeline, payments, pricing and times agree with each other:
+//
+//	charge := factory.NewCompletedCharge(factory.ChargeOptionLocalPrice(
+//		coinbasecommerce.Money{Amount: 25, Currency: "USD"},
+//	))
+//
+// The fixtures can be used as they are in unit tests, encoded like the API
+// encodes them, or added to a fake API of the cbctest package.
+package factory
+
+import (
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+// ChargeOptions contains the options of a charge fixture.
+type ChargeOptions struct {
+	id          string
+	code        string
+	name        string
+	description string
+	localPrice  *coinbasecommerce.Money
+	metadata    map[string]string
+	createdAt   time.Time
+	network     string
+	hostedURL   string
+}
+
+// ChargeOptionFunc represents a function that can modify the contents of the
+// ChargeOptions.
+type ChargeOptionFunc func(*ChargeOptions)
+
+// ChargeOptionID sets the ID of the charge, which is random by default.
+func ChargeOptionID(id string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.id = id
+	}
+}
+
+// ChargeOptionCode sets the code of the charge, which is random by default.
+func ChargeOptionCode(code string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.code = code
+	}
+}
+
+// ChargeOptionName sets the name of the charge.
+func ChargeOptionName(name string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.name = name
+	}
+}
+
+// ChargeOptionDescription sets the description of the charge.
+func ChargeOptionDescription(description string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.description = description
+	}
+}
+
+// ChargeOptionLocalPrice sets the price of the charge in the local
+// currency, which is 100.00 USD by default.
+func ChargeOptionLocalPrice(localPrice coinbasecommerce.Money) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.localPrice = &localPrice
+	}
+}
+
+// ChargeOptionNoPrice makes the charge one without a price, i.e. the
+// customer chooses how much to pay.
+func ChargeOptionNoPrice() ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.localPrice = nil
+	}
+}
+
+// ChargeOptionMetadata sets the metadata of the charge.
+func ChargeOptionMetadata(metadata map[string]string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.metadata = metadata
+	}
+}
+
+// ChargeOptionCreatedAt sets when the charge was created. By default, the
+// charge was created just long enough ago for its last status to be now.
+func ChargeOptionCreatedAt(createdAt time.Time) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.createdAt = createdAt
+	}
+}
+
+// ChargeOptionNetwork sets the network that the charge is paid on, i.e. a
+// key of the pricing of a charge, which is "ethereum" by default.
+func ChargeOptionNetwork(network string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.network = network
+	}
+}
+
+// ChargeOptionHostedURL sets the base URL of the hosted page of the charge,
+// to which its code is appended. It's cbctest.DefaultHostedURL by default.
+func ChargeOptionHostedURL(hostedURL string) ChargeOptionFunc {
+	return func(options *ChargeOptions) {
+		options.hostedURL = hostedURL
+	}
+}
+
+// step is a change of the status of a charge fixture.
+type step struct {
+	// after is how long after the creation of the charge the status changed.
+	after   time.Duration
+	status  coinbasecommerce.ChargeStatus
+	context coinbasecommerce.ChargeStatusUpdateContext
+	// paid is the fraction of the price that was paid when the status
+	// changed, if any.
+	paid float64
+	// confirmed tells whether the pending payments were confirmed when the
+	// status changed.
+	confirmed bool
+}
+
+// Steps of the charge fixtures.
+var (
+	pendingSteps = []step{
+		{after: 5 * time.Minute, status: coinbasecommerce.ChargeStatusPending, paid: 1},
+	}
+	completedSteps = append(pendingSteps[:1:1],
+		step{after: 10 * time.Minute, status: coinbasecommerce.ChargeStatusCompleted, confirmed: true},
+	)
+	underpaidSteps = []step{
+		{after: 5 * time.Minute, status: coinbasecommerce.ChargeStatusPending, paid: 0.5},
+		{
+			after:     10 * time.Minute,
+			status:    coinbasecommerce.ChargeStatusUnresolved,
+			context:   coinbasecommerce.ChargeStatusUpdateContextUnderpaid,
+			confirmed: true,
+		},
+	}
+	overpaidSteps = []step{
+		{after: 5 * time.Minute, status: coinbasecommerce.ChargeStatusPending, paid: 1.5},
+		{
+			after:     10 * time.Minute,
+			status:    coinbasecommerce.ChargeStatusUnresolved,
+			context:   coinbasecommerce.ChargeStatusUpdateContextOverpaid,
+			confirmed: true,
+		},
+	}
+	expiredSteps = []step{
+		{after: cbctest.ChargeLifetime, status: coinbasecommerce.ChargeStatusExpired},
+	}
+	delayedSteps = append(expiredSteps[:1:1],
+		step{
+			after:     cbctest.ChargeLifetime + 5*time.Minute,
+			status:    coinbasecommerce.ChargeStatusUnresolved,
+			context:   coinbasecommerce.ChargeStatusUpdateContextDelayed,
+			paid:      1,
+			confirmed: true,
+		},
+	)
+	canceledSteps = []step{
+		{after: time.Minute, status: coinbasecommerce.ChargeStatusCanceled},
+	}
+	resolvedSteps = append(underpaidSteps[:2:2],
+		step{after: 15 * time.Minute, status: coinbasecommerce.ChargeStatusResolved},
+	)
+)
+
+// NewCharge creates a new charge that hasn't been paid yet.
+func NewCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, nil)
+}
+
+// NewPendingCharge creates a charge whose payment of the full price has
+// been detected, but not yet confirmed.
+func NewPendingCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, pendingSteps)
+}
+
+// NewCompletedCharge creates a charge whose payment of the full price has
+// been confirmed.
+func NewCompletedCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, completedSteps)
+}
+
+// NewUnderpaidCharge creates an unresolved charge that was paid half its
+// price.
+func NewUnderpaidCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, underpaidSteps)
+}
+
+// NewOverpaidCharge creates an unresolved charge that was paid one and a
+// half times its price.
+func NewOverpaidCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, overpaidSteps)
+}
+
+// NewDelayedCharge creates an unresolved charge that was paid in full after
+// it expired.
+func NewDelayedCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, delayedSteps)
+}
+
+// NewExpiredCharge creates a charge that expired without being paid.
+func NewExpiredCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, expiredSteps)
+}
+
+// NewCanceledCharge creates a charge that was canceled before it was paid.
+func NewCanceledCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, canceledSteps)
+}
+
+// NewResolvedCharge creates an underpaid charge that was then resolved by
+// the merchant.
+func NewResolvedCharge(optionFuncs ...ChargeOptionFunc) coinbasecommerce.Charge {
+	return newCharge(optionFuncs, resolvedSteps)
+}
+
+func newCharge(optionFuncs []ChargeOptionFunc, steps []step) coinbasecommerce.Charge {
+	options := ChargeOptions{
+		id:          "",
+		code:        "",
+		name:        "The Sovereign Individual",
+		description: "Mastering the Transition to the Information Age",
+		localPrice:  &coinbasecommerce.Money{Amount: 100, Currency: "USD"},
+		metadata:    nil,
+		createdAt:   time.Time{},
+		network:     "ethereum",
+		hostedURL:   cbctest.DefaultHostedURL,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	if options.id == "" {
+		options.id = cbctest.NewID()
+	}
+	if options.code == "" {
+		options.code = cbctest.NewCode()
+	}
+	if options.createdAt.IsZero() {
+		options.createdAt = time.Now().UTC().Truncate(time.Second)
+		if len(steps) != 0 {
+			options.createdAt = options.createdAt.Add(-steps[len(steps)-1].after)
+		}
+	}
+
+	charge := coinbasecommerce.Charge{
+		ID:          options.id,
+		Resource:    "charge",
+		Code:        options.code,
+		Name:        options.name,
+		Description: options.description,
+		HostedURL:   options.hostedURL + options.code,
+		CreatedAt:   options.createdAt,
+		ExpiresAt:   options.createdAt.Add(cbctest.ChargeLifetime),
+		Timeline: []coinbasecommerce.ChargeStatusUpdate{
+			{Time: options.createdAt, Status: coinbasecommerce.ChargeStatusNew},
+		},
+		Metadata:    options.metadata,
+		PricingType: coinbasecommerce.PricingTypeNone,
+		Pricing:     map[string]coinbasecommerce.Money{},
+		Payments:    []interface{}{},
+	}
+	if charge.Metadata == nil {
+		charge.Metadata = map[string]string{}
+	}
+
+	// the payments of the charges without a price are worth 100.00 USD
+	paymentBase := coinbasecommerce.Money{Amount: 100, Currency: "USD"}
+	if options.localPrice != nil {
+		charge.PricingType = coinbasecommerce.PricingTypeFixed
+		charge.Pricing = cbctest.Pricing(*options.localPrice)
+		paymentBase = *options.localPrice
+	}
+
+	for _, step := range steps {
+		at := options.createdAt.Add(step.after)
+		if step.paid != 0 {
+			local := coinbasecommerce.Money{Amount: paymentBase.Amount * step.paid, Currency: paymentBase.Currency}
+			charge.Payments = append(charge.Payments,
+				NewPayment(options.network, local, cbctest.PaymentStatusPending, at))
+		}
+		if step.confirmed {
+			for i, payment := range charge.Payments {
+				if payment, ok := payment.(cbctest.Payment); ok {
+					charge.Payments[i] = confirmPayment(payment)
+				}
+			}
+		}
+		if step.status == coinbasecommerce.ChargeStatusCompleted {
+			charge.ConfirmedAt = at
+		}
+		charge.Timeline = append(charge.Timeline, coinbasecommerce.ChargeStatusUpdate{
+			Time:    at,
+			Status:  step.status,
+			Context: step.context,
+		})
+	}
+	return charge
+}
@@ -0,0 +1,241 @@
+package factory_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest/factory"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+var (
+	uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	codePattern   = regexp.MustCompile(`^[ABCDEFGHJKLMNPQRSTUVWXYZ23456789]{8}$`)
+)
+
+func TestChargeFixtures(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	newStatus := coinbasecommerce.ChargeStatusNew
+	pending := coinbasecommerce.ChargeStatusPending
+	unresolved := coinbasecommerce.ChargeStatusUnresolved
+
+	tests := []struct {
+		name           string
+		newCharge      func(...factory.ChargeOptionFunc) coinbasecommerce.Charge
+		wantStatuses   []coinbasecommerce.ChargeStatus
+		wantContext    coinbasecommerce.ChargeStatusUpdateContext
+		wantPaid       float64
+		wantConfirmed  bool
+		wantEventType  coinbasecommerce.EventType
+		wantLastStatus time.Duration
+	}{
+		{
+			name:          "new",
+			newCharge:     factory.NewCharge,
+			wantStatuses:  []coinbasecommerce.ChargeStatus{newStatus},
+			wantEventType: coinbasecommerce.EventTypeChargeCreated,
+		},
+		{
+			name:           "pending",
+			newCharge:      factory.NewPendingCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, pending},
+			wantPaid:       1,
+			wantEventType:  coinbasecommerce.EventTypeChargePending,
+			wantLastStatus: 5 * time.Minute,
+		},
+		{
+			name:           "completed",
+			newCharge:      factory.NewCompletedCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, pending, coinbasecommerce.ChargeStatusCompleted},
+			wantPaid:       1,
+			wantConfirmed:  true,
+			wantEventType:  coinbasecommerce.EventTypeChargeConfirmed,
+			wantLastStatus: 10 * time.Minute,
+		},
+		{
+			name:           "underpaid",
+			newCharge:      factory.NewUnderpaidCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, pending, unresolved},
+			wantContext:    coinbasecommerce.ChargeStatusUpdateContextUnderpaid,
+			wantPaid:       0.5,
+			wantConfirmed:  true,
+			wantEventType:  coinbasecommerce.EventTypeChargeFailed,
+			wantLastStatus: 10 * time.Minute,
+		},
+		{
+			name:           "overpaid",
+			newCharge:      factory.NewOverpaidCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, pending, unresolved},
+			wantContext:    coinbasecommerce.ChargeStatusUpdateContextOverpaid,
+			wantPaid:       1.5,
+			wantConfirmed:  true,
+			wantEventType:  coinbasecommerce.EventTypeChargeFailed,
+			wantLastStatus: 10 * time.Minute,
+		},
+		{
+			name:           "delayed",
+			newCharge:      factory.NewDelayedCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, coinbasecommerce.ChargeStatusExpired, unresolved},
+			wantContext:    coinbasecommerce.ChargeStatusUpdateContextDelayed,
+			wantPaid:       1,
+			wantConfirmed:  true,
+			wantEventType:  coinbasecommerce.EventTypeChargeDelayed,
+			wantLastStatus: cbctest.ChargeLifetime + 5*time.Minute,
+		},
+		{
+			name:           "expired",
+			newCharge:      factory.NewExpiredCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, coinbasecommerce.ChargeStatusExpired},
+			wantEventType:  coinbasecommerce.EventTypeChargeFailed,
+			wantLastStatus: cbctest.ChargeLifetime,
+		},
+		{
+			name:           "canceled",
+			newCharge:      factory.NewCanceledCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, coinbasecommerce.ChargeStatusCanceled},
+			wantEventType:  coinbasecommerce.EventTypeChargeCreated,
+			wantLastStatus: time.Minute,
+		},
+		{
+			name:           "resolved",
+			newCharge:      factory.NewResolvedCharge,
+			wantStatuses:   []coinbasecommerce.ChargeStatus{newStatus, pending, unresolved, coinbasecommerce.ChargeStatusResolved},
+			wantPaid:       0.5,
+			wantConfirmed:  true,
+			wantEventType:  coinbasecommerce.EventTypeChargeResolved,
+			wantLastStatus: 15 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charge := tt.newCharge(
+				factory.ChargeOptionCreatedAt(createdAt),
+				factory.ChargeOptionLocalPrice(coinbasecommerce.Money{Amount: 30, Currency: "USD"}),
+			)
+			if !uuidV4Pattern.MatchString(charge.ID) || !codePattern.MatchString(charge.Code) {
+				t.Errorf("ID, code = %q, %q, want a UUID and a code of the API", charge.ID, charge.Code)
+			}
+			if charge.HostedURL != cbctest.DefaultHostedURL+charge.Code {
+				t.Errorf("HostedURL = %s, want %s", charge.HostedURL, cbctest.DefaultHostedURL+charge.Code)
+			}
+			if want := createdAt.Add(cbctest.ChargeLifetime); !charge.ExpiresAt.Equal(want) {
+				t.Errorf("ExpiresAt = %s, want %s", charge.ExpiresAt, want)
+			}
+
+			if len(charge.Timeline) != len(tt.wantStatuses) {
+				t.Fatalf("timeline = %+v, want the statuses %v", charge.Timeline, tt.wantStatuses)
+			}
+			for i, update := range charge.Timeline {
+				if update.Status != tt.wantStatuses[i] {
+					t.Errorf("status %d = %s, want %s", i, update.Status, tt.wantStatuses[i])
+				}
+				if i > 0 && update.Time.Before(charge.Timeline[i-1].Time) {
+					t.Errorf("status %d changed at %s, before the previous one", i, update.Time)
+				}
+			}
+			last := charge.Timeline[len(charge.Timeline)-1]
+			if want := createdAt.Add(tt.wantLastStatus); !last.Time.Equal(want) {
+				t.Errorf("last status changed at %s, want %s", last.Time, want)
+			}
+			if tt.wantContext != "" {
+				// the context is that of the unresolved status
+				var context coinbasecommerce.ChargeStatusUpdateContext
+				for _, update := range charge.Timeline {
+					if update.Status == unresolved {
+						context = update.Context
+					}
+				}
+				if context != tt.wantContext {
+					t.Errorf("context = %s, want %s", context, tt.wantContext)
+				}
+			}
+			if completed := last.Status == coinbasecommerce.ChargeStatusCompleted; completed != !charge.ConfirmedAt.IsZero() {
+				t.Errorf("ConfirmedAt = %s, want it to be set only if the charge is completed", charge.ConfirmedAt)
+			} else if completed && !charge.ConfirmedAt.Equal(last.Time) {
+				t.Errorf("ConfirmedAt = %s, want %s", charge.ConfirmedAt, last.Time)
+			}
+
+			if tt.wantPaid == 0 {
+				if len(charge.Payments) != 0 {
+					t.Errorf("payments = %+v, want none", charge.Payments)
+				}
+			} else {
+				if len(charge.Payments) != 1 {
+					t.Fatalf("payments = %+v, want 1", charge.Payments)
+				}
+				payment := charge.Payments[0].(cbctest.Payment)
+				if payment.Value.Local.Amount != 30*tt.wantPaid || payment.Network != "ethereum" {
+					t.Errorf("payment = %+v, want %v USD on ethereum", payment.Value, 30*tt.wantPaid)
+				}
+				if confirmed := payment.Status == cbctest.PaymentStatusConfirmed &&
+					payment.Block.ConfirmationsAccumulated == cbctest.ConfirmationsRequired; confirmed != tt.wantConfirmed {
+					t.Errorf("payment %s with %d confirmations, want confirmed %v",
+						payment.Status, payment.Block.ConfirmationsAccumulated, tt.wantConfirmed)
+				}
+			}
+
+			event := factory.NewChargeEvent(charge)
+			if event.Type != tt.wantEventType || !event.CreatedAt.Equal(last.Time) {
+				t.Errorf("NewChargeEvent() = %s at %s, want %s at %s", event.Type, event.CreatedAt, tt.wantEventType, last.Time)
+			}
+		})
+	}
+}
+
+func TestChargeFixtureDefaults(t *testing.T) {
+	before := time.Now().UTC().Truncate(time.Second)
+	charge := factory.NewCompletedCharge()
+	after := time.Now().UTC()
+
+	// the last status of the charge changed now
+	last := charge.Timeline[len(charge.Timeline)-1].Time
+	if last.Before(before) || last.After(after) {
+		t.Errorf("last status changed at %s, want between %s and %s", last, before, after)
+	}
+	if charge.PricingType != coinbasecommerce.PricingTypeFixed || charge.Pricing["local"].Amount != 100 {
+		t.Errorf("pricing = %s %+v, want a fixed price of 100 USD", charge.PricingType, charge.Pricing)
+	}
+	if other := factory.NewCompletedCharge(); other.ID == charge.ID || other.Code == charge.Code {
+		t.Errorf("fixtures share the ID %s or the code %s", charge.ID, charge.Code)
+	}
+
+	noPrice := factory.NewCharge(factory.ChargeOptionNoPrice())
+	if noPrice.PricingType != coinbasecommerce.PricingTypeNone || len(noPrice.Pricing) != 0 {
+		t.Errorf("pricing = %s %+v, want no price", noPrice.PricingType, noPrice.Pricing)
+	}
+}
+
+func TestChargeFixturesInFakeAPI(t *testing.T) {
+	clock := cbctest.NewManualClock(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+	server := cbctest.NewServer(cbctest.OptionClock(clock))
+	defer server.Close()
+	apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+
+	underpaid := server.AddCharge(factory.NewUnderpaidCharge(factory.ChargeOptionCreatedAt(clock.Now())))
+	fresh := server.AddCharge(factory.NewCharge(factory.ChargeOptionCreatedAt(clock.Now())))
+
+	resolved, _, err := charges.Resolve(apiCallContext, underpaid.Code)
+	if err != nil {
+		t.Fatalf("Resolve() of an underpaid fixture error = %v", err)
+	}
+	if status := resolved.Timeline[len(resolved.Timeline)-1].Status; status != coinbasecommerce.ChargeStatusResolved {
+		t.Errorf("status = %s, want %s", status, coinbasecommerce.ChargeStatusResolved)
+	}
+
+	// the fixture expires when the fake API would have expired it
+	clock.Advance(cbctest.ChargeLifetime)
+	expired, _, err := charges.Get(apiCallContext, fresh.Code)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if status := expired.Timeline[len(expired.Timeline)-1].Status; status != coinbasecommerce.ChargeStatusExpired {
+		t.Errorf("status after the lifetime = %s, want %s", status, coinbasecommerce.ChargeStatusExpired)
+	}
+	if _, _, err := charges.Cancel(apiCallContext, fresh.Code); !errors.Is(err, coinbasecommerce.ErrAPIInvalidRequest) {
+		t.Errorf("Cancel() of an expired fixture error = %v, want %v", err, coinbasecommerce.ErrAPIInvalidRequest)
+	}
+}
@@ -0,0 +1,99 @@
+package factory
+
+import (
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+// CheckoutOptions contains the options of a checkout fixture.
+type CheckoutOptions struct {
+	id            string
+	name          string
+	description   string
+	localPrice    *coinbasecommerce.Money
+	requestedInfo []coinbasecommerce.RequestableInfo
+}
+
+// CheckoutOptionFunc represents a function that can modify the contents of
+// the CheckoutOptions.
+type CheckoutOptionFunc func(*CheckoutOptions)
+
+// CheckoutOptionID sets the ID of the checkout, which is random by default.
+func CheckoutOptionID(id string) CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.id = id
+	}
+}
+
+// CheckoutOptionName sets the name of the checkout.
+func CheckoutOptionName(name string) CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.name = name
+	}
+}
+
+// CheckoutOptionDescription sets the description of the checkout.
+func CheckoutOptionDescription(description string) CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.description = description
+	}
+}
+
+// CheckoutOptionLocalPrice sets the price of the checkout in the local
+// currency, which is 100.00 USD by default.
+func CheckoutOptionLocalPrice(localPrice coinbasecommerce.Money) CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.localPrice = &localPrice
+	}
+}
+
+// CheckoutOptionNoPrice makes the checkout one without a price, e.g. for
+// donations.
+func CheckoutOptionNoPrice() CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.localPrice = nil
+	}
+}
+
+// CheckoutOptionRequestedInfo sets the information that's requested from
+// the customers, which is their name and email by default.
+func CheckoutOptionRequestedInfo(requestedInfo ...coinbasecommerce.RequestableInfo) CheckoutOptionFunc {
+	return func(options *CheckoutOptions) {
+		options.requestedInfo = requestedInfo
+	}
+}
+
+// NewCheckout creates a new checkout.
+func NewCheckout(optionFuncs ...CheckoutOptionFunc) coinbasecommerce.Checkout {
+	options := CheckoutOptions{
+		id:          "",
+		name:        "The Sovereign Individual",
+		description: "Mastering the Transition to the Information Age",
+		localPrice:  &coinbasecommerce.Money{Amount: 100, Currency: "USD"},
+		requestedInfo: []coinbasecommerce.RequestableInfo{
+			coinbasecommerce.RequestableInfoName,
+			coinbasecommerce.RequestableInfoEmail,
+		},
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	if options.id == "" {
+		options.id = cbctest.NewID()
+	}
+	checkout := coinbasecommerce.Checkout{
+		ID:            options.id,
+		Resource:      "checkout",
+		Name:          options.name,
+		Description:   options.description,
+		PricingType:   coinbasecommerce.PricingTypeNone,
+		RequestedInfo: options.requestedInfo,
+	}
+	if options.localPrice != nil {
+		localPrice := *options.localPrice
+		checkout.PricingType = coinbasecommerce.PricingTypeFixed
+		checkout.LocalPrice = &localPrice
+	}
+	return checkout
+}
@@ -0,0 +1,88 @@
+package factory
+
+import (
+	"encoding/json"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+// NewEvent creates a webhook event of the type about the charge, which
+// happened when the last status of the charge was set.
+func NewEvent(eventType coinbasecommerce.EventType, charge coinbasecommerce.Charge) coinbasecommerce.Event {
+	event := coinbasecommerce.Event{
+		ID:         cbctest.NewID(),
+		Resource:   "event",
+		Type:       eventType,
+		APIVersion: cbctest.APIVersion,
+		CreatedAt:  charge.CreatedAt,
+		Data:       charge,
+	}
+	if len(charge.Timeline) != 0 {
+		event.CreatedAt = charge.Timeline[len(charge.Timeline)-1].Time
+	}
+	return event
+}
+
+// NewChargeEvent creates the webhook event that's sent when the charge gets
+// its last status, e.g. a "charge:confirmed" event for a completed charge.
+// The statuses without an event of their own, i.e. NEW and CANCELED, get a
+// "charge:created" event.
+func NewChargeEvent(charge coinbasecommerce.Charge) coinbasecommerce.Event {
+	eventType := coinbasecommerce.EventTypeChargeCreated
+	if len(charge.Timeline) != 0 {
+		update := charge.Timeline[len(charge.Timeline)-1]
+		switch update.Status {
+		case coinbasecommerce.ChargeStatusPending:
+			eventType = coinbasecommerce.EventTypeChargePending
+		case coinbasecommerce.ChargeStatusCompleted:
+			eventType = coinbasecommerce.EventTypeChargeConfirmed
+		case coinbasecommerce.ChargeStatusExpired:
+			eventType = coinbasecommerce.EventTypeChargeFailed
+		case coinbasecommerce.ChargeStatusUnresolved:
+			eventType = coinbasecommerce.EventTypeChargeFailed
+			if update.Context == coinbasecommerce.ChargeStatusUpdateContextDelayed {
+				eventType = coinbasecommerce.EventTypeChargeDelayed
+			}
+		case coinbasecommerce.ChargeStatusResolved:
+			eventType = coinbasecommerce.EventTypeChargeResolved
+		}
+	}
+	return NewEvent(eventType, charge)
+}
+
+// NewWebhookPayload creates the body of the first webhook request that
+// delivers the event.
+func NewWebhookPayload(event coinbasecommerce.Event) webhooks.Payload {
+	return webhooks.Payload{
+		ID:            1,
+		ScheduledFor:  event.CreatedAt,
+		Event:         event,
+		AttemptNumber: 1,
+	}
+}
+
+// JSON encodes the fixture the way that the API encodes it. It panics if
+// the fixture can't be encoded.
+func JSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// ResponseJSON encodes the fixture as the body of a successful response of
+// the API, i.e. wrapped in `data`.
+func ResponseJSON(data interface{}) []byte {
+	return JSON(map[string]interface{}{"data": data})
+}
+
+// SignedWebhookPayload encodes the webhook payload of the event, and signs
+// it with the shared secret. The signature is the value of the
+// `X-CC-Webhook-Signature` header.
+func SignedWebhookPayload(event coinbasecommerce.Event, secret string) (payload []byte, signature string) {
+	payload = JSON(NewWebhookPayload(event))
+	return payload, webhooks.Sign(payload, secret)
+}
@@ -0,0 +1,32 @@
+package factory_test
+
+import (
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce/cbctest/factory"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+func TestSignedWebhookPayload(t *testing.T) {
+	charge := factory.NewCompletedCharge()
+	event := factory.NewChargeEvent(charge)
+	payload, signature := factory.SignedWebhookPayload(event, "secret")
+
+	if err := webhooks.NewVerifier("secret").Verify(payload, signature); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	parsed, err := webhooks.ParsePayload(payload)
+	if err != nil {
+		t.Fatalf("ParsePayload() error = %v", err)
+	}
+	if parsed.Event.ID != event.ID || parsed.Event.Type != event.Type || parsed.AttemptNumber != 1 {
+		t.Errorf("parsed payload = %+v, want the first delivery of %+v", parsed, event)
+	}
+	if !uuidV4Pattern.MatchString(parsed.Event.ID) {
+		t.Errorf("event ID = %q, want a UUID", parsed.Event.ID)
+	}
+	decoded, ok := parsed.Event.Charge()
+	if !ok || decoded.ID != charge.ID || decoded.Code != charge.Code || !decoded.ExpiresAt.Equal(charge.ExpiresAt) {
+		t.Errorf("event data = %+v, want the charge %s", parsed.Event.Data, charge.ID)
+	}
+}
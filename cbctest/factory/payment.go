@@ -0,0 +1,58 @@
+package factory
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+// NewPayment creates a payment on the network, e.g. "bitcoin", whose value
+// in the local currency is local, and whose value in the cryptocurrency of
+// the network is derived from it. A confirmed payment has all the
+// confirmations that it requires.
+func NewPayment(
+	network string,
+	local coinbasecommerce.Money,
+	status string,
+	detectedAt time.Time,
+) cbctest.Payment {
+	crypto, ok := cbctest.Pricing(local)[network]
+	if !ok || network == "local" {
+		panic(`invalid network. valid values: "bitcoin", "bitcoincash", "ethereum", "litecoin", "usdc", "dai"`)
+	}
+
+	height, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		panic(fmt.Sprintf("factory: failed to generate a block height: %v", err))
+	}
+	payment := cbctest.Payment{
+		Network:       network,
+		TransactionID: "0x" + cbctest.NewHash(),
+		Status:        status,
+		DetectedAt:    detectedAt,
+		Value: cbctest.PaymentValue{
+			Local:  local,
+			Crypto: crypto,
+		},
+		Block: cbctest.PaymentBlock{
+			Height:                   1000000 + int(height.Int64()),
+			Hash:                     "0x" + cbctest.NewHash(),
+			ConfirmationsAccumulated: 0,
+			ConfirmationsRequired:    cbctest.ConfirmationsRequired,
+		},
+	}
+	if status == cbctest.PaymentStatusConfirmed {
+		payment = confirmPayment(payment)
+	}
+	return payment
+}
+
+func confirmPayment(payment cbctest.Payment) cbctest.Payment {
+	payment.Status = cbctest.PaymentStatusConfirmed
+	payment.Block.ConfirmationsAccumulated = payment.Block.ConfirmationsRequired
+	return payment
+}
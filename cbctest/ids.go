@@ -0,0 +1,43 @@
+package cbctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewID generates a random ID of a resource, e.g. a charge, which is a UUID
+// like the IDs of the API.
+func NewID() string {
+	b := randomBytes(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// NewHash generates a random hex-encoded hash, e.g. of a transaction.
+func NewHash() string {
+	return hex.EncodeToString(randomBytes(32))
+}
+
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewCode generates a random code of a charge, e.g. "66BEOV2A".
+func NewCode() string {
+	b := randomBytes(8)
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
+
+// randomBytes reads n random bytes. It panics if the system's secure random
+// number generator fails, since no fake data can be made without it.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("cbctest: failed to read random bytes: %v", err))
+	}
+	return b
+}
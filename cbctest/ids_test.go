@@ -0,0 +1,41 @@
+package cbctest_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+var (
+	uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	codePattern   = regexp.MustCompile(`^[ABCDEFGHJKLMNPQRSTUVWXYZ23456789]{8}$`)
+	hashPattern   = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+func TestNewIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		newID   func() string
+		pattern *regexp.Regexp
+	}{
+		{"NewID", cbctest.NewID, uuidV4Pattern},
+		{"NewCode", cbctest.NewCode, codePattern},
+		{"NewHash", cbctest.NewHash, hashPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for i := 0; i < 1000; i++ {
+				id := tt.newID()
+				if !tt.pattern.MatchString(id) {
+					t.Fatalf("%s() = %q, want it to match %s", tt.name, id, tt.pattern)
+				}
+				if seen[id] {
+					t.Fatalf("%s() = %q twice", tt.name, id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cbctest
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+const (
+	defaultPaginationLimit = 25
+	maxPaginationLimit     = 100
+)
+
+// paginate selects the page of the resources that's requested by the query
+// of the request, given the IDs of all the resources in the order that they
+// were created. It returns the indexes of the selected IDs.
+func paginate(r *http.Request, ids []string) ([]int, *coinbasecommerce.Pagination, *response) {
+	query := r.URL.Query()
+	invalid := func(message string) ([]int, *coinbasecommerce.Pagination, *response) {
+		resp := errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeInvalidRequest, message)
+		return nil, nil, &resp
+	}
+
+	order := coinbasecommerce.PaginationOrderDesc
+	switch o := query.Get("order"); o {
+	case "", string(coinbasecommerce.PaginationOrderDesc):
+	case string(coinbasecommerce.PaginationOrderAsc):
+		order = coinbasecommerce.PaginationOrderAsc
+	default:
+		return invalid("Invalid order: " + o)
+	}
+
+	limit := defaultPaginationLimit
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 || limit > maxPaginationLimit {
+			return invalid("Invalid limit: " + l)
+		}
+		if limit == 0 {
+			limit = defaultPaginationLimit
+		}
+	}
+
+	ordered := make([]int, len(ids))
+	for i := range ordered {
+		if order == coinbasecommerce.PaginationOrderAsc {
+			ordered[i] = i
+		} else {
+			ordered[i] = len(ids) - 1 - i
+		}
+	}
+	position := func(id string) int {
+		for i, index := range ordered {
+			if ids[index] == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	pagination := coinbasecommerce.Pagination{
+		Order:       string(order),
+		Total:       len(ids),
+		Limit:       limit,
+		CursorRange: []string{},
+	}
+	start, end := 0, limit
+	if startingAfter := query.Get("starting_after"); startingAfter != "" {
+		p := position(startingAfter)
+		if p < 0 {
+			return invalid("Invalid starting_after: " + startingAfter)
+		}
+		pagination.StartingAfter = &startingAfter
+		start, end = p+1, p+1+limit
+	} else if endingBefore := query.Get("ending_before"); endingBefore != "" {
+		p := position(endingBefore)
+		if p < 0 {
+			return invalid("Invalid ending_before: " + endingBefore)
+		}
+		pagination.EndingBefore = &endingBefore
+		start, end = p-limit, p
+		if start < 0 {
+			start = 0
+		}
+	}
+	if end > len(ordered) {
+		end = len(ordered)
+	}
+
+	page := ordered[start:end]
+	pagination.Yielded = len(page)
+	if len(page) != 0 {
+		first, last := ids[page[0]], ids[page[len(page)-1]]
+		pagination.CursorRange = []string{first, last}
+		if start > 0 {
+			previousURI := pageURI(r, order, limit, "ending_before", first)
+			pagination.PreviousURI = &previousURI
+		}
+		if end < len(ordered) {
+			nextURI := pageURI(r, order, limit, "starting_after", last)
+			pagination.NextURI = &nextURI
+		}
+	}
+	return page, &pagination, nil
+}
+
+func pageURI(r *http.Request, order coinbasecommerce.PaginationOrder, limit int, cursor, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	values := url.Values{
+		"order": {string(order)},
+		"limit": {strconv.Itoa(limit)},
+		cursor:  {id},
+	}
+	return scheme + "://" + r.Host + r.URL.Path + "?" + values.Encode()
+}
@@ -0,0 +1,46 @@
+package cbctest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Seed contains the charges and checkouts that a fake API starts with.
+type Seed struct {
+	Charges   []coinbasecommerce.Charge   `json:"charges"`
+	Checkouts []coinbasecommerce.Checkout `json:"checkouts"`
+}
+
+// LoadSeed reads a seed from a JSON file, e.g.
+//
+//	{
+//	  "charges": [{"name": "T-shirt", "pricing_type": "fixed_price", ...}],
+//	  "checkouts": [{"name": "Donation", "pricing_type": "no_price", ...}]
+//	}
+func LoadSeed(path string) (Seed, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Seed{}, err
+	}
+	var seed Seed
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return Seed{}, err
+	}
+	return seed, nil
+}
+
+// Seed adds the charges and checkouts of the seed to the fake API, like
+// AddCharge and AddCheckout do.
+func (server *Server) Seed(seed Seed) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for _, charge := range seed.Charges {
+		server.addCharge(charge)
+	}
+	for _, checkout := range seed.Checkouts {
+		server.addCheckout(checkout)
+	}
+}
@@ -0,0 +1,325 @@
+// Package cbctest provides a fake Coinbase Commerce API that keeps its charges
+// and checkouts in memory, so that the code that uses this library can be
+// tested end to end without a network connection or an account:
+//
+//	server := cbctest.NewServer()
+//	defer server.Close()
+//
+//	apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+//	charge, _, err := charges.Create(apiCallContext, charges.CreateRequest{...})
+//
+// The fake responds with the same envelopes as the API, i.e. `data`,
+// `pagination`, `error` and `warnings`, checks the API key, paginates the
+// lists with cursors, and enforces the status rules of the charges, e.g.
+// only new charges can be canceled.
+//
+// The payments of the charges are simulated by Simulate, e.g. to make a
+// charge UNRESOLVED because it was underpaid, and the new charges expire
+// according to the clock of the fake, which can be a ManualClock. If a
+// webhook is set, the events of the charges are signed and sent to it.
+package cbctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Default credentials of the fake API.
+const (
+	APIKey     = "cbctest-api-key"
+	APIVersion = "2018-03-22"
+)
+
+// DefaultHostedURL is the base URL of the hosted pages of the charges.
+const DefaultHostedURL = "https://commerce.coinbase.com/charges/"
+
+// maxRequestBodySize is the maximum size of the body of a request.
+const maxRequestBodySize = 1 << 20
+
+// Server is a fake Coinbase Commerce API. It's safe for concurrent use by
+// multiple goroutines.
+type Server struct {
+	apiKeys       []string
+	hostedURL     string
+	clock         Clock
+	webhookURL    string
+	webhookSecret string
+	webhookClient *http.Client
+
+	httpServer *httptest.Server
+
+	mu                sync.Mutex
+	charges           []*coinbasecommerce.Charge
+	checkouts         []*coinbasecommerce.Checkout
+	redirectURLs      map[string][2]string
+	blockHeight       int
+	webhookSequence   int
+	webhookDeliveries []WebhookDelivery
+}
+
+// OptionFunc represents a function that can modify the contents of the Server
+// before it's used.
+type OptionFunc func(*Server)
+
+// OptionAPIKeys replaces the API keys that the fake accepts, which is only
+// APIKey by default.
+func OptionAPIKeys(apiKeys ...string) OptionFunc {
+	if len(apiKeys) == 0 {
+		panic("apiKeys cannot be empty")
+	}
+	return func(server *Server) {
+		server.apiKeys = apiKeys
+	}
+}
+
+// OptionHostedURL sets the base URL of the hosted pages of the charges, to
+// which the code of a charge is appended. It's DefaultHostedURL by default.
+func OptionHostedURL(hostedURL string) OptionFunc {
+	return func(server *Server) {
+		server.hostedURL = hostedURL
+	}
+}
+
+// OptionClock sets the clock of the fake API, e.g. a ManualClock to make
+// the charges expire without waiting. The system clock is used by default.
+func OptionClock(clock Clock) OptionFunc {
+	return func(server *Server) {
+		server.clock = clock
+	}
+}
+
+// New creates a new fake API that isn't listening; it's served by ServeHTTP.
+func New(optionFuncs ...OptionFunc) *Server {
+	server := Server{
+		apiKeys:       []string{APIKey},
+		hostedURL:     DefaultHostedURL,
+		clock:         systemClock{},
+		webhookClient: http.DefaultClient,
+		redirectURLs:  make(map[string][2]string),
+		blockHeight:   1000000,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&server)
+	}
+	return &server
+}
+
+// NewServer creates a new fake API and starts serving it on a local port
+// until Close is called.
+func NewServer(optionFuncs ...OptionFunc) *Server {
+	server := New(optionFuncs...)
+	server.httpServer = httptest.NewServer(server)
+	return server
+}
+
+// URL returns the base URL of the fake API, if it was started by NewServer.
+func (server *Server) URL() string {
+	if server.httpServer == nil {
+		return ""
+	}
+	return server.httpServer.URL
+}
+
+// Close stops serving the fake API, if it was started by NewServer.
+func (server *Server) Close() {
+	if server.httpServer != nil {
+		server.httpServer.Close()
+	}
+}
+
+// APIConfig creates an API configuration that sends the requests to the fake
+// API, if it was started by NewServer, with its first API key.
+func (server *Server) APIConfig(optionFuncs ...coinbasecommerce.APIConfigOptionFunc) *coinbasecommerce.APIConfig {
+	optionFuncs = append(
+		[]coinbasecommerce.APIConfigOptionFunc{coinbasecommerce.APIConfigOptionBaseURL(server.URL())},
+		optionFuncs...,
+	)
+	return coinbasecommerce.NewAPIConfig(server.apiKeys[0], APIVersion, optionFuncs...)
+}
+
+// envelope is the body of every response of the API.
+type envelope struct {
+	Data       interface{}                  `json:"data,omitempty"`
+	Pagination *coinbasecommerce.Pagination `json:"pagination,omitempty"`
+	Error      *coinbasecommerce.APIError   `json:"error,omitempty"`
+	Warnings   coinbasecommerce.Warnings    `json:"warnings,omitempty"`
+}
+
+// response is what a handler of an endpoint responds with.
+type response struct {
+	statusCode int
+	envelope   envelope
+}
+
+func dataResponse(data interface{}) response {
+	return response{statusCode: http.StatusOK, envelope: envelope{Data: data}}
+}
+
+func errorResponse(statusCode int, errorType, message string) response {
+	return response{
+		statusCode: statusCode,
+		envelope: envelope{
+			Error: &coinbasecommerce.APIError{Type: errorType, Message: message},
+		},
+	}
+}
+
+func notFoundResponse() response {
+	return errorResponse(http.StatusNotFound, coinbasecommerce.APIErrorTypeNotFound, "Not found")
+}
+
+// ServeHTTP serves a request to the fake API.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(coinbasecommerce.APIHeaderRequestID, NewID())
+
+	// the charges expire whenever the fake API is used
+	var events []coinbasecommerce.Event
+	server.mu.Lock()
+	server.expireCharges(&events)
+	server.mu.Unlock()
+	server.deliver(events)
+
+	resp := server.serve(r)
+	if r.Header.Get(coinbasecommerce.APIHeaderVersion) == "" {
+		resp.envelope.Warnings = append(resp.envelope.Warnings,
+			"Missing X-CC-Version header; serving latest API version ("+APIVersion+")")
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(resp.statusCode)
+	json.NewEncoder(w).Encode(&resp.envelope)
+}
+
+func (server *Server) serve(r *http.Request) response {
+	if !server.authenticate(r.Header.Get(coinbasecommerce.APIHeaderAPIKey)) {
+		return errorResponse(http.StatusUnauthorized, coinbasecommerce.APIErrorTypeAuthentication, "No such API key.")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			return notFoundResponse()
+		}
+		segments = append(segments, segment)
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "charges":
+		switch r.Method {
+		case http.MethodGet:
+			return server.listCharges(r)
+		case http.MethodPost:
+			return server.createCharge(r)
+		}
+	case len(segments) == 2 && segments[0] == "charges" && r.Method == http.MethodGet:
+		return server.getCharge(segments[1])
+	case len(segments) == 3 && segments[0] == "charges" && r.Method == http.MethodPost:
+		switch segments[2] {
+		case "cancel":
+			return server.cancelCharge(segments[1])
+		case "resolve":
+			return server.resolveCharge(segments[1])
+		}
+	case len(segments) == 1 && segments[0] == "checkouts":
+		switch r.Method {
+		case http.MethodGet:
+			return server.listCheckouts(r)
+		case http.MethodPost:
+			return server.createCheckout(r)
+		}
+	case len(segments) == 2 && segments[0] == "checkouts":
+		switch r.Method {
+		case http.MethodGet:
+			return server.getCheckout(segments[1])
+		case http.MethodPut:
+			return server.updateCheckout(r, segments[1])
+		case http.MethodDelete:
+			return server.deleteCheckout(segments[1])
+		}
+	}
+	return notFoundResponse()
+}
+
+func (server *Server) authenticate(apiKey string) bool {
+	if apiKey == "" {
+		return false
+	}
+	for _, key := range server.apiKeys {
+		if key == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody decodes the JSON body of the request into v.
+func decodeBody(r *http.Request, v interface{}) *response {
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodySize)).Decode(v); err != nil {
+		resp := errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeInvalidRequest,
+			fmt.Sprintf("Invalid JSON body: %s", err))
+		return &resp
+	}
+	return nil
+}
+
+// validator collects the problems with the fields of a request.
+type validator struct {
+	missing     []string
+	fieldErrors []coinbasecommerce.APIFieldError
+}
+
+func (v *validator) required(field string, present bool) {
+	if !present {
+		v.missing = append(v.missing, field)
+	}
+}
+
+func (v *validator) check(field string, ok bool, message string) {
+	if !ok {
+		v.fieldErrors = append(v.fieldErrors, coinbasecommerce.APIFieldError{Field: field, Message: message})
+	}
+}
+
+func (v *validator) response() *response {
+	var resp response
+	switch {
+	case len(v.missing) != 0:
+		resp = errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeParamRequired,
+			"Required parameter missing: "+strings.Join(v.missing, ", "))
+	case len(v.fieldErrors) != 0:
+		resp = errorResponse(http.StatusBadRequest, coinbasecommerce.APIErrorTypeValidation,
+			v.fieldErrors[0].Field+" "+v.fieldErrors[0].Message)
+		resp.envelope.Error.Errors = v.fieldErrors
+	default:
+		return nil
+	}
+	return &resp
+}
+
+func validatePricing(v *validator, pricingType coinbasecommerce.PricingType, localPrice *coinbasecommerce.Money) {
+	v.required("pricing_type", pricingType != "")
+	switch pricingType {
+	case "", coinbasecommerce.PricingTypeNone:
+	case coinbasecommerce.PricingTypeFixed:
+		v.required("local_price", localPrice != nil)
+		if localPrice != nil {
+			v.check("local_price", localPrice.Amount > 0, "amount must be greater than 0")
+			v.check("local_price", localPrice.Currency != "", "currency is required")
+		}
+	default:
+		v.check("pricing_type", false, "must be one of no_price, fixed_price")
+	}
+}
+
+func (server *Server) now() time.Time {
+	return server.clock.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,323 @@
+package cbctest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+func TestServerChargeStatusRules(t *testing.T) {
+	cancel := func(apiCallContext coinbasecommerce.APICallContext, code string) (coinbasecommerce.Charge, error) {
+		charge, _, err := charges.Cancel(apiCallContext, code)
+		return charge, err
+	}
+	resolve := func(apiCallContext coinbasecommerce.APICallContext, code string) (coinbasecommerce.Charge, error) {
+		charge, _, err := charges.Resolve(apiCallContext, code)
+		return charge, err
+	}
+	type step struct {
+		name       string
+		call       func(coinbasecommerce.APICallContext, string) (coinbasecommerce.Charge, error)
+		wantStatus coinbasecommerce.ChargeStatus
+		wantErr    error
+	}
+
+	tests := []struct {
+		name     string
+		scenario cbctest.Scenario
+		steps    []step
+	}{
+		{
+			name: "cancel a new charge twice",
+			steps: []step{
+				{"first cancel", cancel, coinbasecommerce.ChargeStatusCanceled, nil},
+				{"second cancel", cancel, "", coinbasecommerce.ErrAPIInvalidRequest},
+			},
+		},
+		{
+			name: "resolve a new charge",
+			steps: []step{
+				{"resolve", resolve, "", coinbasecommerce.ErrAPIInvalidRequest},
+				{"cancel", cancel, coinbasecommerce.ChargeStatusCanceled, nil},
+				{"resolve after cancel", resolve, "", coinbasecommerce.ErrAPIInvalidRequest},
+			},
+		},
+		{
+			name:     "resolve an unresolved charge twice",
+			scenario: cbctest.ScenarioUnderpaid,
+			steps: []step{
+				{"cancel", cancel, "", coinbasecommerce.ErrAPIInvalidRequest},
+				{"first resolve", resolve, coinbasecommerce.ChargeStatusResolved, nil},
+				{"second resolve", resolve, "", coinbasecommerce.ErrAPIInvalidRequest},
+			},
+		},
+		{
+			name:     "cancel a pending charge",
+			scenario: cbctest.ScenarioPending,
+			steps: []step{
+				{"cancel", cancel, "", coinbasecommerce.ErrAPIInvalidRequest},
+				{"resolve", resolve, "", coinbasecommerce.ErrAPIInvalidRequest},
+			},
+		},
+		{
+			name:     "cancel and resolve a completed charge",
+			scenario: cbctest.ScenarioCompleted,
+			steps: []step{
+				{"cancel", cancel, "", coinbasecommerce.ErrAPIInvalidRequest},
+				{"resolve", resolve, "", coinbasecommerce.ErrAPIInvalidRequest},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cbctest.NewServer()
+			defer server.Close()
+			apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+
+			charge := server.AddCharge(coinbasecommerce.Charge{
+				Pricing: map[string]coinbasecommerce.Money{"local": {Amount: 10, Currency: "USD"}},
+			})
+			if tt.scenario != "" {
+				if _, err := server.Simulate(charge.Code, tt.scenario); err != nil {
+					t.Fatalf("Simulate(%s) error = %v", tt.scenario, err)
+				}
+			}
+			for _, step := range tt.steps {
+				before, _ := server.Charge(charge.Code)
+				got, err := step.call(apiCallContext, charge.Code)
+				if step.wantErr != nil {
+					var apiError coinbasecommerce.APIError
+					if !errors.Is(err, step.wantErr) || !errors.As(err, &apiError) || apiError.StatusCode != http.StatusBadRequest {
+						t.Fatalf("%s: error = %v, want a 400 %v", step.name, err, step.wantErr)
+					}
+					if after, _ := server.Charge(charge.Code); len(after.Timeline) != len(before.Timeline) {
+						t.Errorf("%s: timeline = %+v, want it unchanged", step.name, after.Timeline)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s: error = %v", step.name, err)
+				}
+				if status := got.Timeline[len(got.Timeline)-1].Status; status != step.wantStatus {
+					t.Errorf("%s: status = %s, want %s", step.name, status, step.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestServerChargeNotFound(t *testing.T) {
+	server := cbctest.NewServer()
+	defer server.Close()
+	apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+
+	if _, _, err := charges.Get(apiCallContext, "missing"); !errors.Is(err, coinbasecommerce.ErrAPINotFound) {
+		t.Errorf("Get() error = %v, want %v", err, coinbasecommerce.ErrAPINotFound)
+	}
+	if _, _, err := charges.Cancel(apiCallContext, "missing"); !errors.Is(err, coinbasecommerce.ErrAPINotFound) {
+		t.Errorf("Cancel() error = %v, want %v", err, coinbasecommerce.ErrAPINotFound)
+	}
+	if _, _, err := charges.Resolve(apiCallContext, "missing"); !errors.Is(err, coinbasecommerce.ErrAPINotFound) {
+		t.Errorf("Resolve() error = %v, want %v", err, coinbasecommerce.ErrAPINotFound)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server := cbctest.NewServer(cbctest.OptionAPIKeys("key-1", "key-2"))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		wantErr error
+	}{
+		{"first key", "key-1", nil},
+		{"second key", "key-2", nil},
+		{"unknown key", cbctest.APIKey, coinbasecommerce.ErrAPIAuthentication},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiCallContext := coinbasecommerce.NewAPICallContext(coinbasecommerce.NewAPIConfig(
+				tt.apiKey, cbctest.APIVersion, coinbasecommerce.APIConfigOptionBaseURL(server.URL())))
+			_, _, _, err := charges.List(apiCallContext, coinbasecommerce.NewPaginationOption())
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("List() error = %v", err)
+			} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("List() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerPagination(t *testing.T) {
+	server := cbctest.NewServer()
+	defer server.Close()
+	apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, server.AddCharge(coinbasecommerce.Charge{}).ID)
+	}
+
+	tests := []struct {
+		name           string
+		optionFuncs    []coinbasecommerce.PaginationOptionFunc
+		wantIDs        []string
+		wantPrevious   string
+		wantNext       string
+		wantInvalidErr bool
+	}{
+		{
+			name:    "newest first by default",
+			wantIDs: []string{ids[4], ids[3], ids[2], ids[1], ids[0]},
+		},
+		{
+			name:        "first page",
+			optionFuncs: []coinbasecommerce.PaginationOptionFunc{coinbasecommerce.PaginationOptionLimit(2)},
+			wantIDs:     []string{ids[4], ids[3]},
+			wantNext:    ids[3],
+		},
+		{
+			name: "middle page",
+			optionFuncs: []coinbasecommerce.PaginationOptionFunc{
+				coinbasecommerce.PaginationOptionLimit(2),
+				coinbasecommerce.PaginationOptionStartingAfter(ids[3]),
+			},
+			wantIDs:      []string{ids[2], ids[1]},
+			wantPrevious: ids[2],
+			wantNext:     ids[1],
+		},
+		{
+			name: "last page",
+			optionFuncs: []coinbasecommerce.PaginationOptionFunc{
+				coinbasecommerce.PaginationOptionLimit(2),
+				coinbasecommerce.PaginationOptionStartingAfter(ids[1]),
+			},
+			wantIDs:      []string{ids[0]},
+			wantPrevious: ids[0],
+		},
+		{
+			name: "page before a cursor",
+			optionFuncs: []coinbasecommerce.PaginationOptionFunc{
+				coinbasecommerce.PaginationOptionLimit(2),
+				coinbasecommerce.PaginationOptionEndingBefore(ids[1]),
+			},
+			wantIDs:      []string{ids[3], ids[2]},
+			wantPrevious: ids[3],
+			wantNext:     ids[2],
+		},
+		{
+			name: "oldest first",
+			optionFuncs: []coinbasecommerce.PaginationOptionFunc{
+				coinbasecommerce.PaginationOptionOrder(coinbasecommerce.PaginationOrderAsc),
+				coinbasecommerce.PaginationOptionLimit(3),
+			},
+			wantIDs:  []string{ids[0], ids[1], ids[2]},
+			wantNext: ids[2],
+		},
+		{
+			name:           "unknown starting after",
+			optionFuncs:    []coinbasecommerce.PaginationOptionFunc{coinbasecommerce.PaginationOptionStartingAfter("missing")},
+			wantInvalidErr: true,
+		},
+		{
+			name:           "unknown ending before",
+			optionFuncs:    []coinbasecommerce.PaginationOptionFunc{coinbasecommerce.PaginationOptionEndingBefore("missing")},
+			wantInvalidErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, pagination, _, err := charges.List(apiCallContext, coinbasecommerce.NewPaginationOption(tt.optionFuncs...))
+			if tt.wantInvalidErr {
+				if !errors.Is(err, coinbasecommerce.ErrAPIInvalidRequest) {
+					t.Errorf("List() error = %v, want %v", err, coinbasecommerce.ErrAPIInvalidRequest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			var gotIDs []string
+			for _, charge := range listed {
+				gotIDs = append(gotIDs, charge.ID)
+			}
+			if !equalStrings(gotIDs, tt.wantIDs) {
+				t.Errorf("List() IDs = %v, want %v", gotIDs, tt.wantIDs)
+			}
+			if pagination.Total != len(ids) || pagination.Yielded != len(tt.wantIDs) {
+				t.Errorf("total, yielded = %d, %d, want %d, %d",
+					pagination.Total, pagination.Yielded, len(ids), len(tt.wantIDs))
+			}
+			wantCursorRange := []string{tt.wantIDs[0], tt.wantIDs[len(tt.wantIDs)-1]}
+			if !equalStrings(pagination.CursorRange, wantCursorRange) {
+				t.Errorf("cursor range = %v, want %v", pagination.CursorRange, wantCursorRange)
+			}
+			assertPageURI(t, "previous", pagination.PreviousURI, "ending_before", tt.wantPrevious)
+			assertPageURI(t, "next", pagination.NextURI, "starting_after", tt.wantNext)
+		})
+	}
+}
+
+func TestServerUnknownRoute(t *testing.T) {
+	server := cbctest.NewServer()
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodDelete, server.URL()+"/charges/ABC", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(coinbasecommerce.APIHeaderAPIKey, cbctest.APIKey)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("status code = %d, want %d", response.StatusCode, http.StatusNotFound)
+	}
+	if response.Header.Get(coinbasecommerce.APIHeaderRequestID) == "" {
+		t.Errorf("header %s is missing", coinbasecommerce.APIHeaderRequestID)
+	}
+}
+
+// assertPageURI checks that the URI of a page is set to the one with the
+// cursor, or isn't set if wantID is empty.
+func assertPageURI(t *testing.T, name string, uri *string, cursor, wantID string) {
+	t.Helper()
+	if wantID == "" {
+		if uri != nil {
+			t.Errorf("%s URI = %s, want none", name, *uri)
+		}
+		return
+	}
+	if uri == nil {
+		t.Errorf("%s URI = nil, want one with %s=%s", name, cursor, wantID)
+		return
+	}
+	parsed, err := url.Parse(*uri)
+	if err != nil {
+		t.Fatalf("%s URI %s: %v", name, *uri, err)
+	}
+	if got := parsed.Query().Get(cursor); got != wantID {
+		t.Errorf("%s URI = %s, want %s=%s", name, *uri, cursor, wantID)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,312 @@
+package cbctest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Scenario is a way that the payment of a charge can go.
+type Scenario string
+
+// Scenario constants.
+const (
+	// ScenarioPending detects a payment of the full price of a new charge,
+	// which makes the charge PENDING.
+	ScenarioPending Scenario = "pending"
+	// ScenarioCompleted confirms the payment of a pending charge, or detects
+	// and then confirms a payment of the full price of a new charge, which
+	// makes the charge COMPLETED.
+	ScenarioCompleted Scenario = "completed"
+	// ScenarioUnderpaid detects and then confirms a payment of half the price
+	// of a new charge, which makes the charge UNRESOLVED (UNDERPAID).
+	ScenarioUnderpaid Scenario = "underpaid"
+	// ScenarioOverpaid detects and then confirms a payment of one and a half
+	// times the price of a new charge, which makes the charge UNRESOLVED
+	// (OVERPAID).
+	ScenarioOverpaid Scenario = "overpaid"
+	// ScenarioDelayed expires a new charge, and then detects and confirms a
+	// payment of its full price, which makes the charge UNRESOLVED (DELAYED).
+	ScenarioDelayed Scenario = "delayed"
+	// ScenarioExpired expires a new charge before its expiration time.
+	ScenarioExpired Scenario = "expired"
+)
+
+// Scenarios are all the scenarios that can be simulated.
+var Scenarios = []Scenario{
+	ScenarioPending,
+	ScenarioCompleted,
+	ScenarioUnderpaid,
+	ScenarioOverpaid,
+	ScenarioDelayed,
+	ScenarioExpired,
+}
+
+// Errors of the simulations.
+var (
+	ErrChargeNotFound    = errors.New("charge not found")
+	ErrInvalidScenario   = errors.New("invalid scenario")
+	ErrInvalidTransition = errors.New("invalid transition")
+)
+
+// Payment status constants.
+const (
+	PaymentStatusPending   = "PENDING"
+	PaymentStatusConfirmed = "CONFIRMED"
+)
+
+// ConfirmationsRequired is the number of blocks that confirm a payment.
+const ConfirmationsRequired = 2
+
+// Payment is a payment of a charge on a blockchain, as it's listed in the
+// payments of the charge.
+type Payment struct {
+	Network       string       `json:"network"`
+	TransactionID string       `json:"transaction_id"`
+	Status        string       `json:"status"`
+	DetectedAt    time.Time    `json:"detected_at"`
+	Value         PaymentValue `json:"value"`
+	Block         PaymentBlock `json:"block"`
+}
+
+// PaymentValue is the value of a payment in the local currency of the
+// charge and in the paid cryptocurrency.
+type PaymentValue struct {
+	Local  coinbasecommerce.Money `json:"local"`
+	Crypto coinbasecommerce.Money `json:"crypto"`
+}
+
+// PaymentBlock is the block of the transaction of a payment.
+type PaymentBlock struct {
+	Height                   int    `json:"height"`
+	Hash                     string `json:"hash"`
+	ConfirmationsAccumulated int    `json:"confirmations_accumulated"`
+	ConfirmationsRequired    int    `json:"confirmations_required"`
+}
+
+// SimulateOptions contains the options of a simulation.
+type SimulateOptions struct {
+	network string
+	amount  *coinbasecommerce.Money
+}
+
+// SimulateOptionFunc represents a function that can modify the contents of
+// the SimulateOptions.
+type SimulateOptionFunc func(*SimulateOptions)
+
+// SimulateOptionNetwork sets the network that the payment is made on, i.e.
+// a key of the pricing of a charge, e.g. "bitcoin". It's "ethereum" by
+// default.
+func SimulateOptionNetwork(network string) SimulateOptionFunc {
+	if _, ok := exchangeRate(network); !ok {
+		panic(`invalid network. valid values: "bitcoin", "bitcoincash", "ethereum", "litecoin", "usdc", "dai"`)
+	}
+	return func(options *SimulateOptions) {
+		options.network = network
+	}
+}
+
+// SimulateOptionAmount sets the value of the payment in the local currency,
+// instead of the one that the scenario derives from the price of the charge.
+func SimulateOptionAmount(amount coinbasecommerce.Money) SimulateOptionFunc {
+	return func(options *SimulateOptions) {
+		options.amount = &amount
+	}
+}
+
+// defaultPaymentAmount is the value of the payments of the charges that
+// don't have a price.
+var defaultPaymentAmount = coinbasecommerce.Money{Amount: 100, Currency: "USD"}
+
+// Simulate makes the payment of the charge with the ID or code go the way of
+// the scenario. Every change of the status of the charge is appended to its
+// timeline, and sent to the webhook if it's set. The charge is returned as
+// it is after the simulation, even if it failed halfway.
+func (server *Server) Simulate(
+	idOrCode string,
+	scenario Scenario,
+	optionFuncs ...SimulateOptionFunc,
+) (coinbasecommerce.Charge, error) {
+	options := SimulateOptions{
+		network: "ethereum",
+		amount:  nil,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	var events []coinbasecommerce.Event
+	defer func() { server.deliver(events) }()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.expireCharges(&events)
+	charge := server.findCharge(idOrCode)
+	if charge == nil {
+		return coinbasecommerce.Charge{}, fmt.Errorf("%w: %s", ErrChargeNotFound, idOrCode)
+	}
+	err := server.simulate(charge, scenario, options, &events)
+	return cloneCharge(charge), err
+}
+
+func (server *Server) simulate(
+	charge *coinbasecommerce.Charge,
+	scenario Scenario,
+	options SimulateOptions,
+	events *[]coinbasecommerce.Event,
+) error {
+	status := chargeStatus(charge)
+	invalid := func() error {
+		return fmt.Errorf("%w: %s charge cannot be %s", ErrInvalidTransition, status, scenario)
+	}
+
+	localPrice, hasPrice := charge.Pricing["local"]
+	if !hasPrice {
+		localPrice = defaultPaymentAmount
+	}
+	amount := func(factor float64) coinbasecommerce.Money {
+		if options.amount != nil {
+			return *options.amount
+		}
+		return coinbasecommerce.Money{Amount: localPrice.Amount * factor, Currency: localPrice.Currency}
+	}
+
+	switch scenario {
+	case ScenarioPending:
+		if status != coinbasecommerce.ChargeStatusNew {
+			return invalid()
+		}
+		server.detectPayment(charge, options.network, amount(1), events)
+	case ScenarioCompleted:
+		switch status {
+		case coinbasecommerce.ChargeStatusNew:
+			server.detectPayment(charge, options.network, amount(1), events)
+		case coinbasecommerce.ChargeStatusPending:
+		default:
+			return invalid()
+		}
+		server.confirmPayments(charge)
+		charge.ConfirmedAt = server.now()
+		server.setStatus(charge, coinbasecommerce.ChargeStatusCompleted, "",
+			coinbasecommerce.EventTypeChargeConfirmed, events)
+	case ScenarioUnderpaid, ScenarioOverpaid:
+		if status != coinbasecommerce.ChargeStatusNew || !hasPrice {
+			return invalid()
+		}
+		factor, context := 0.5, coinbasecommerce.ChargeStatusUpdateContextUnderpaid
+		if scenario == ScenarioOverpaid {
+			factor, context = 1.5, coinbasecommerce.ChargeStatusUpdateContextOverpaid
+		}
+		server.detectPayment(charge, options.network, amount(factor), events)
+		server.confirmPayments(charge)
+		server.setStatus(charge, coinbasecommerce.ChargeStatusUnresolved, context,
+			coinbasecommerce.EventTypeChargeFailed, events)
+	case ScenarioDelayed:
+		switch status {
+		case coinbasecommerce.ChargeStatusNew:
+			server.setStatus(charge, coinbasecommerce.ChargeStatusExpired, "",
+				coinbasecommerce.EventTypeChargeFailed, events)
+		case coinbasecommerce.ChargeStatusExpired:
+		default:
+			return invalid()
+		}
+		server.addPayment(charge, options.network, amount(1))
+		server.confirmPayments(charge)
+		server.setStatus(charge, coinbasecommerce.ChargeStatusUnresolved,
+			coinbasecommerce.ChargeStatusUpdateContextDelayed, coinbasecommerce.EventTypeChargeDelayed, events)
+	case ScenarioExpired:
+		if status != coinbasecommerce.ChargeStatusNew {
+			return invalid()
+		}
+		server.setStatus(charge, coinbasecommerce.ChargeStatusExpired, "",
+			coinbasecommerce.EventTypeChargeFailed, events)
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidScenario, scenario)
+	}
+	return nil
+}
+
+// setStatus appends the status to the timeline of the charge, and the event
+// about it to events.
+func (server *Server) setStatus(
+	charge *coinbasecommerce.Charge,
+	status coinbasecommerce.ChargeStatus,
+	context coinbasecommerce.ChargeStatusUpdateContext,
+	eventType coinbasecommerce.EventType,
+	events *[]coinbasecommerce.Event,
+) {
+	charge.Timeline = append(charge.Timeline, coinbasecommerce.ChargeStatusUpdate{
+		Time:    server.now(),
+		Status:  status,
+		Context: context,
+	})
+	server.newEvent(eventType, charge, events)
+}
+
+// detectPayment adds a pending payment to the charge, which makes the charge
+// PENDING.
+func (server *Server) detectPayment(
+	charge *coinbasecommerce.Charge,
+	network string,
+	amount coinbasecommerce.Money,
+	events *[]coinbasecommerce.Event,
+) {
+	server.addPayment(charge, network, amount)
+	server.setStatus(charge, coinbasecommerce.ChargeStatusPending, "",
+		coinbasecommerce.EventTypeChargePending, events)
+}
+
+func (server *Server) addPayment(charge *coinbasecommerce.Charge, network string, amount coinbasecommerce.Money) {
+	rate, _ := exchangeRate(network)
+	server.blockHeight++
+	charge.Payments = append(charge.Payments, Payment{
+		Network:       network,
+		TransactionID: "0x" + NewHash(),
+		Status:        PaymentStatusPending,
+		DetectedAt:    server.now(),
+		Value: PaymentValue{
+			Local: amount,
+			Crypto: coinbasecommerce.Money{
+				Amount:   math.Round(amount.Amount/rate.rate*1e8) / 1e8,
+				Currency: rate.currency,
+			},
+		},
+		Block: PaymentBlock{
+			Height:                   server.blockHeight,
+			Hash:                     "0x" + NewHash(),
+			ConfirmationsAccumulated: 0,
+			ConfirmationsRequired:    ConfirmationsRequired,
+		},
+	})
+}
+
+// confirmPayments confirms the pending payments of the charge.
+func (server *Server) confirmPayments(charge *coinbasecommerce.Charge) {
+	for i, payment := range charge.Payments {
+		if payment, ok := payment.(Payment); ok && payment.Status == PaymentStatusPending {
+			payment.Status = PaymentStatusConfirmed
+			payment.Block.ConfirmationsAccumulated = payment.Block.ConfirmationsRequired
+			charge.Payments[i] = payment
+		}
+	}
+}
+
+// expireCharges expires the new charges whose expiration time has passed.
+// The caller must hold the lock of the server.
+func (server *Server) expireCharges(events *[]coinbasecommerce.Event) {
+	now := server.now()
+	for _, charge := range server.charges {
+		if chargeStatus(charge) != coinbasecommerce.ChargeStatusNew || now.Before(charge.ExpiresAt) {
+			continue
+		}
+		charge.Timeline = append(charge.Timeline, coinbasecommerce.ChargeStatusUpdate{
+			Time:   charge.ExpiresAt,
+			Status: coinbasecommerce.ChargeStatusExpired,
+		})
+		server.newEvent(coinbasecommerce.EventTypeChargeFailed, charge, events)
+	}
+}
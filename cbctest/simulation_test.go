@@ -0,0 +1,386 @@
+package cbctest_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+func TestServerSimulate(t *testing.T) {
+	tests := []struct {
+		name           string
+		scenario       cbctest.Scenario
+		optionFuncs    []cbctest.SimulateOptionFunc
+		wantStatus     coinbasecommerce.ChargeStatus
+		wantContext    coinbasecommerce.ChargeStatusUpdateContext
+		wantEventTypes []coinbasecommerce.EventType
+		wantPayment    *cbctest.Payment
+	}{
+		{
+			name:           "pending",
+			scenario:       cbctest.ScenarioPending,
+			wantStatus:     coinbasecommerce.ChargeStatusPending,
+			wantEventTypes: []coinbasecommerce.EventType{coinbasecommerce.EventTypeChargePending},
+			wantPayment: &cbctest.Payment{
+				Network: "ethereum",
+				Status:  cbctest.PaymentStatusPending,
+				Value: cbctest.PaymentValue{
+					Local:  coinbasecommerce.Money{Amount: 30, Currency: "USD"},
+					Crypto: coinbasecommerce.Money{Amount: 0.01, Currency: coinbasecommerce.CurrencyEthereum},
+				},
+			},
+		},
+		{
+			name:       "completed",
+			scenario:   cbctest.ScenarioCompleted,
+			wantStatus: coinbasecommerce.ChargeStatusCompleted,
+			wantEventTypes: []coinbasecommerce.EventType{
+				coinbasecommerce.EventTypeChargePending,
+				coinbasecommerce.EventTypeChargeConfirmed,
+			},
+			wantPayment: &cbctest.Payment{
+				Network: "ethereum",
+				Status:  cbctest.PaymentStatusConfirmed,
+				Value: cbctest.PaymentValue{
+					Local:  coinbasecommerce.Money{Amount: 30, Currency: "USD"},
+					Crypto: coinbasecommerce.Money{Amount: 0.01, Currency: coinbasecommerce.CurrencyEthereum},
+				},
+			},
+		},
+		{
+			name:        "underpaid",
+			scenario:    cbctest.ScenarioUnderpaid,
+			wantStatus:  coinbasecommerce.ChargeStatusUnresolved,
+			wantContext: coinbasecommerce.ChargeStatusUpdateContextUnderpaid,
+			wantEventTypes: []coinbasecommerce.EventType{
+				coinbasecommerce.EventTypeChargePending,
+				coinbasecommerce.EventTypeChargeFailed,
+			},
+			wantPayment: &cbctest.Payment{
+				Network: "ethereum",
+				Status:  cbctest.PaymentStatusConfirmed,
+				Value: cbctest.PaymentValue{
+					Local:  coinbasecommerce.Money{Amount: 15, Currency: "USD"},
+					Crypto: coinbasecommerce.Money{Amount: 0.005, Currency: coinbasecommerce.CurrencyEthereum},
+				},
+			},
+		},
+		{
+			name:        "overpaid on another network",
+			scenario:    cbctest.ScenarioOverpaid,
+			optionFuncs: []cbctest.SimulateOptionFunc{cbctest.SimulateOptionNetwork("bitcoin")},
+			wantStatus:  coinbasecommerce.ChargeStatusUnresolved,
+			wantContext: coinbasecommerce.ChargeStatusUpdateContextOverpaid,
+			wantEventTypes: []coinbasecommerce.EventType{
+				coinbasecommerce.EventTypeChargePending,
+				coinbasecommerce.EventTypeChargeFailed,
+			},
+			wantPayment: &cbctest.Payment{
+				Network: "bitcoin",
+				Status:  cbctest.PaymentStatusConfirmed,
+				Value: cbctest.PaymentValue{
+					Local:  coinbasecommerce.Money{Amount: 45, Currency: "USD"},
+					Crypto: coinbasecommerce.Money{Amount: 0.0009, Currency: coinbasecommerce.CurrencyBitcoin},
+				},
+			},
+		},
+		{
+			name:        "delayed with another amount",
+			scenario:    cbctest.ScenarioDelayed,
+			optionFuncs: []cbctest.SimulateOptionFunc{cbctest.SimulateOptionAmount(coinbasecommerce.Money{Amount: 60, Currency: "USD"})},
+			wantStatus:  coinbasecommerce.ChargeStatusUnresolved,
+			wantContext: coinbasecommerce.ChargeStatusUpdateContextDelayed,
+			wantEventTypes: []coinbasecommerce.EventType{
+				coinbasecommerce.EventTypeChargeFailed,
+				coinbasecommerce.EventTypeChargeDelayed,
+			},
+			wantPayment: &cbctest.Payment{
+				Network: "ethereum",
+				Status:  cbctest.PaymentStatusConfirmed,
+				Value: cbctest.PaymentValue{
+					Local:  coinbasecommerce.Money{Amount: 60, Currency: "USD"},
+					Crypto: coinbasecommerce.Money{Amount: 0.02, Currency: coinbasecommerce.CurrencyEthereum},
+				},
+			},
+		},
+		{
+			name:           "expired",
+			scenario:       cbctest.ScenarioExpired,
+			wantStatus:     coinbasecommerce.ChargeStatusExpired,
+			wantEventTypes: []coinbasecommerce.EventType{coinbasecommerce.EventTypeChargeFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := newWebhookRecorder(t)
+			defer webhook.Close()
+			server := cbctest.New(cbctest.OptionWebhook(webhook.URL, testWebhookSecret))
+			added := server.AddCharge(coinbasecommerce.Charge{
+				Pricing: map[string]coinbasecommerce.Money{"local": {Amount: 30, Currency: "USD"}},
+			})
+
+			charge, err := server.Simulate(added.Code, tt.scenario, tt.optionFuncs...)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			last := charge.Timeline[len(charge.Timeline)-1]
+			if last.Status != tt.wantStatus || last.Context != tt.wantContext {
+				t.Errorf("status = %s (%s), want %s (%s)", last.Status, last.Context, tt.wantStatus, tt.wantContext)
+			}
+			if stored, _ := server.Charge(added.ID); len(stored.Timeline) != len(charge.Timeline) {
+				t.Errorf("stored timeline = %+v, want %+v", stored.Timeline, charge.Timeline)
+			}
+
+			if tt.wantPayment == nil {
+				if len(charge.Payments) != 0 {
+					t.Errorf("payments = %+v, want none", charge.Payments)
+				}
+			} else {
+				if len(charge.Payments) != 1 {
+					t.Fatalf("payments = %+v, want 1", charge.Payments)
+				}
+				payment := charge.Payments[0].(cbctest.Payment)
+				if payment.Network != tt.wantPayment.Network || payment.Status != tt.wantPayment.Status ||
+					payment.Value != tt.wantPayment.Value {
+					t.Errorf("payment = %+v, want %+v", payment, *tt.wantPayment)
+				}
+				if payment.Block.ConfirmationsRequired != cbctest.ConfirmationsRequired {
+					t.Errorf("confirmations required = %d, want %d",
+						payment.Block.ConfirmationsRequired, cbctest.ConfirmationsRequired)
+				}
+			}
+
+			events := webhook.Events()
+			if len(events) != len(tt.wantEventTypes) {
+				t.Fatalf("events = %+v, want the types %v", events, tt.wantEventTypes)
+			}
+			for i, event := range events {
+				if event.Type != tt.wantEventTypes[i] {
+					t.Errorf("event %d type = %s, want %s", i, event.Type, tt.wantEventTypes[i])
+				}
+				if eventCharge, ok := event.Charge(); !ok || eventCharge.ID != added.ID {
+					t.Errorf("event %d data = %+v, want the charge %s", i, event.Data, added.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestServerSimulateInvalid(t *testing.T) {
+	server := cbctest.New()
+	priced := func() string {
+		return server.AddCharge(coinbasecommerce.Charge{
+			Pricing: map[string]coinbasecommerce.Money{"local": {Amount: 30, Currency: "USD"}},
+		}).Code
+	}
+	simulated := func(scenario cbctest.Scenario) string {
+		code := priced()
+		if _, err := server.Simulate(code, scenario); err != nil {
+			t.Fatalf("Simulate(%s) error = %v", scenario, err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		scenario cbctest.Scenario
+		wantErr  error
+	}{
+		{"unknown charge", "missing", cbctest.ScenarioCompleted, cbctest.ErrChargeNotFound},
+		{"unknown scenario", priced(), "refunded", cbctest.ErrInvalidScenario},
+		{"underpaid without a price", server.AddCharge(coinbasecommerce.Charge{}).Code, cbctest.ScenarioUnderpaid, cbctest.ErrInvalidTransition},
+		{"pending twice", simulated(cbctest.ScenarioPending), cbctest.ScenarioPending, cbctest.ErrInvalidTransition},
+		{"completed twice", simulated(cbctest.ScenarioCompleted), cbctest.ScenarioCompleted, cbctest.ErrInvalidTransition},
+		{"expired after completed", simulated(cbctest.ScenarioCompleted), cbctest.ScenarioExpired, cbctest.ErrInvalidTransition},
+		{"underpaid after expired", simulated(cbctest.ScenarioExpired), cbctest.ScenarioUnderpaid, cbctest.ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := server.Charge(tt.code)
+			charge, err := server.Simulate(tt.code, tt.scenario)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Simulate() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(charge.Timeline) != len(before.Timeline) {
+				t.Errorf("timeline = %+v, want it unchanged", charge.Timeline)
+			}
+		})
+	}
+}
+
+func TestServerSimulateDelayedAfterExpiry(t *testing.T) {
+	server := cbctest.New()
+	code := server.AddCharge(coinbasecommerce.Charge{}).Code
+	if _, err := server.Simulate(code, cbctest.ScenarioExpired); err != nil {
+		t.Fatalf("Simulate(expired) error = %v", err)
+	}
+	charge, err := server.Simulate(code, cbctest.ScenarioDelayed)
+	if err != nil {
+		t.Fatalf("Simulate(delayed) error = %v", err)
+	}
+
+	wantStatuses := []coinbasecommerce.ChargeStatus{
+		coinbasecommerce.ChargeStatusNew,
+		coinbasecommerce.ChargeStatusExpired,
+		coinbasecommerce.ChargeStatusUnresolved,
+	}
+	if len(charge.Timeline) != len(wantStatuses) {
+		t.Fatalf("timeline = %+v, want the statuses %v", charge.Timeline, wantStatuses)
+	}
+	for i, update := range charge.Timeline {
+		if update.Status != wantStatuses[i] {
+			t.Errorf("status %d = %s, want %s", i, update.Status, wantStatuses[i])
+		}
+	}
+	// the charges without a price are paid the default amount
+	if payment := charge.Payments[0].(cbctest.Payment); payment.Value.Local.Amount != 100 {
+		t.Errorf("payment = %+v, want 100 USD", payment.Value.Local)
+	}
+}
+
+func TestServerChargeExpiry(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	clock := cbctest.NewManualClock(createdAt)
+	webhook := newWebhookRecorder(t)
+	defer webhook.Close()
+	server := cbctest.NewServer(
+		cbctest.OptionClock(clock),
+		cbctest.OptionWebhook(webhook.URL, testWebhookSecret),
+	)
+	defer server.Close()
+	apiCallContext := coinbasecommerce.NewAPICallContext(server.APIConfig())
+
+	charge := server.AddCharge(coinbasecommerce.Charge{})
+	if want := createdAt.Add(cbctest.ChargeLifetime); !charge.ExpiresAt.Equal(want) {
+		t.Fatalf("ExpiresAt = %s, want %s", charge.ExpiresAt, want)
+	}
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		wantStatus coinbasecommerce.ChargeStatus
+		wantEvents int
+	}{
+		{"before the expiration", cbctest.ChargeLifetime - time.Second, coinbasecommerce.ChargeStatusNew, 0},
+		{"at the expiration", time.Second, coinbasecommerce.ChargeStatusExpired, 1},
+		{"after the expiration", time.Hour, coinbasecommerce.ChargeStatusExpired, 1},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		got, _, err := charges.Get(apiCallContext, charge.Code)
+		if err != nil {
+			t.Fatalf("%s: Get() error = %v", step.name, err)
+		}
+		last := got.Timeline[len(got.Timeline)-1]
+		if last.Status != step.wantStatus {
+			t.Errorf("%s: status = %s, want %s", step.name, last.Status, step.wantStatus)
+		}
+		if step.wantStatus == coinbasecommerce.ChargeStatusExpired && !last.Time.Equal(charge.ExpiresAt) {
+			t.Errorf("%s: expired at %s, want %s", step.name, last.Time, charge.ExpiresAt)
+		}
+		if events := webhook.Events(); len(events) != step.wantEvents {
+			t.Errorf("%s: events = %+v, want %d", step.name, events, step.wantEvents)
+		}
+	}
+
+	if _, _, err := charges.Cancel(apiCallContext, charge.Code); !errors.Is(err, coinbasecommerce.ErrAPIInvalidRequest) {
+		t.Errorf("Cancel() of an expired charge error = %v, want %v", err, coinbasecommerce.ErrAPIInvalidRequest)
+	}
+}
+
+func TestServerWebhookDeliveries(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+	server := cbctest.NewServer(cbctest.OptionWebhook(webhook.URL, testWebhookSecret))
+	defer server.Close()
+
+	created, _, err := charges.Create(coinbasecommerce.NewAPICallContext(server.APIConfig()), charges.CreateRequest{
+		Name:        "Coffee",
+		Description: "A cup of coffee",
+		PricingType: coinbasecommerce.PricingTypeNone,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := server.Simulate(created.ID, cbctest.ScenarioExpired); err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	wantEventTypes := []coinbasecommerce.EventType{
+		coinbasecommerce.EventTypeChargeCreated,
+		coinbasecommerce.EventTypeChargeFailed,
+	}
+	deliveries := server.WebhookDeliveries()
+	if len(deliveries) != len(wantEventTypes) {
+		t.Fatalf("deliveries = %+v, want the event types %v", deliveries, wantEventTypes)
+	}
+	for i, delivery := range deliveries {
+		if delivery.Event.Type != wantEventTypes[i] {
+			t.Errorf("delivery %d event type = %s, want %s", i, delivery.Event.Type, wantEventTypes[i])
+		}
+		if delivery.StatusCode != http.StatusInternalServerError || delivery.Err == nil {
+			t.Errorf("delivery %d = %d, %v, want a failed delivery with status code %d",
+				i, delivery.StatusCode, delivery.Err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// webhookRecorder is a webhook that accepts the events that are signed with
+// testWebhookSecret, and records them in the order that they're received.
+type webhookRecorder struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []coinbasecommerce.Event
+}
+
+func newWebhookRecorder(t *testing.T) *webhookRecorder {
+	verifier := webhooks.NewVerifier(testWebhookSecret)
+	recorder := &webhookRecorder{}
+	recorder.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("webhook failed to read the body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := verifier.VerifyHeader(body, r.Header); err != nil {
+			t.Errorf("webhook received an event with an invalid signature: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		payload, err := webhooks.ParsePayload(body)
+		if err != nil {
+			t.Errorf("webhook received an invalid payload: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		recorder.mu.Lock()
+		recorder.events = append(recorder.events, payload.Event)
+		recorder.mu.Unlock()
+	}))
+	return recorder
+}
+
+// Events returns the events that the webhook received.
+func (recorder *webhookRecorder) Events() []coinbasecommerce.Event {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return append([]coinbasecommerce.Event(nil), recorder.events...)
+}
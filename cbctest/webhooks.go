@@ -0,0 +1,120 @@
+package cbctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+// WebhookDelivery is the outcome of sending an event to the webhook.
+type WebhookDelivery struct {
+	Event coinbasecommerce.Event
+	// StatusCode is the status code of the response of the webhook; zero if
+	// the event couldn't be sent.
+	StatusCode int
+	// Err is why the event couldn't be sent, or why the webhook didn't
+	// accept it.
+	Err error
+}
+
+// OptionWebhook sets the URL that the fake API sends the events of the
+// charges to, and the shared secret that they're signed with. Events are
+// only sent if the option is set.
+func OptionWebhook(url, secret string) OptionFunc {
+	return func(server *Server) {
+		server.webhookURL = url
+		server.webhookSecret = secret
+	}
+}
+
+// OptionWebhookClient sets the HTTP client that sends the events to the
+// webhook. http.DefaultClient is used by default.
+func OptionWebhookClient(client *http.Client) OptionFunc {
+	return func(server *Server) {
+		server.webhookClient = client
+	}
+}
+
+// WebhookDeliveries returns the outcomes of sending the events to the
+// webhook, in the order that they were sent.
+func (server *Server) WebhookDeliveries() []WebhookDelivery {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return append([]WebhookDelivery(nil), server.webhookDeliveries...)
+}
+
+// newEvent appends an event about the charge to events, if the webhook is
+// set. The caller must hold the lock of the server.
+func (server *Server) newEvent(
+	eventType coinbasecommerce.EventType,
+	charge *coinbasecommerce.Charge,
+	events *[]coinbasecommerce.Event,
+) {
+	if server.webhookURL == "" {
+		return
+	}
+	*events = append(*events, coinbasecommerce.Event{
+		ID:         NewID(),
+		Resource:   "event",
+		Type:       eventType,
+		APIVersion: APIVersion,
+		CreatedAt:  server.now(),
+		Data:       cloneCharge(charge),
+	})
+}
+
+// deliver sends the events to the webhook one by one. The caller must not
+// hold the lock of the server.
+func (server *Server) deliver(events []coinbasecommerce.Event) {
+	for _, event := range events {
+		server.mu.Lock()
+		server.webhookSequence++
+		payload := webhooks.Payload{
+			ID:            server.webhookSequence,
+			ScheduledFor:  event.CreatedAt,
+			Event:         event,
+			AttemptNumber: 1,
+		}
+		server.mu.Unlock()
+
+		delivery := WebhookDelivery{Event: event}
+		delivery.StatusCode, delivery.Err = server.send(payload)
+
+		server.mu.Lock()
+		server.webhookDeliveries = append(server.webhookDeliveries, delivery)
+		server.mu.Unlock()
+	}
+}
+
+func (server *Server) send(payload webhooks.Payload) (int, error) {
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, server.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhooks.SignatureHeader, webhooks.Sign(body, server.webhookSecret))
+
+	response, err := server.webhookClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook responded with %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
@@ -1,16 +1,14 @@
 package charges
 
 import (
-	"encoding/json"
-	"fmt"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	cancelOperation      = "charges.Cancel"
 	cancelEndpointMethod = "POST"
-	cancelEndpointFmt    = "https://api.commerce.coinbase.com/charges/%s/cancel"
+	cancelEndpointFmt    = "/charges/%s/cancel"
 )
 
 // Cancel cancels a charge object using the Coinbase Commerce API.
@@ -25,27 +23,17 @@ func Cancel(
 			}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var charge coinbasecommerce.Charge
+	responseBody := internal.APIResponse{Data: &charge}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		cancelOperation,
 		cancelEndpointMethod,
-		fmt.Sprintf(cancelEndpointFmt, idOrCode),
-	)
-	if err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Charge   coinbasecommerce.Charge    `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), cancelEndpointFmt, idOrCode),
+		&responseBody,
+	); err != nil {
+		return coinbasecommerce.Charge{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Charge, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return charge, responseBody.Warnings, nil
 }
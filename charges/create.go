@@ -24,17 +24,41 @@ type CreateRequest struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	RedirectURL string            `json:"redirect_url,omitempty"`
 	CancelURL   string            `json:"cancel_url,omitempty"`
+	// IdempotencyKey identifies the charge across the retries of its creation
+	// (optional). It's recorded in the metadata of the charge.
+	IdempotencyKey string `json:"-"`
 }
 
 const (
+	createOperation      = "charges.Create"
 	createEndpointMethod = "POST"
-	createEndpoint       = "https://api.commerce.coinbase.com/charges"
+	createEndpoint       = "/charges"
 )
 
 // Create creates a charge by sending a request to the Coinbase Commerce API.
+//
+// If the request has an idempotency key, the charge that was already created
+// with the same key is returned instead, if there's any. It's looked up in the
+// idempotency store of the API call context, and then among the most recently
+// created charges. In this case, the creation is also retried according to
+// the retry policy of the API call context, looking the charge up again
+// before every attempt. The lookups aren't described by the response metadata,
+// which is of the creation only; its Attempts is 0 if the charge was found
+// before its creation was attempted.
 func Create(
 	apiCallContext coinbasecommerce.APICallContext,
 	request CreateRequest,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	if request.IdempotencyKey != "" {
+		return createIdempotent(apiCallContext, request)
+	}
+	return create(apiCallContext, request)
+}
+
+func create(
+	apiCallContext coinbasecommerce.APICallContext,
+	request CreateRequest,
+	optionFuncs ...internal.APIRequestOptionsFunc,
 ) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
 	bodyBuffer := new(bytes.Buffer)
 	err := json.NewEncoder(bodyBuffer).Encode(request)
@@ -43,28 +67,21 @@ func Create(
 			coinbasecommerce.LocalError{Inner: err}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var charge coinbasecommerce.Charge
+	responseBody := internal.APIResponse{Data: &charge}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		createOperation,
 		createEndpointMethod,
-		createEndpoint,
-		internal.CreateAndDoHTTPRequestOptionsJSONBody(bodyBuffer),
-	)
-	if err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Charge   coinbasecommerce.Charge    `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), createEndpoint),
+		&responseBody,
+		append(
+			[]internal.APIRequestOptionsFunc{internal.APIRequestOptionsJSONBody(bodyBuffer.Bytes())},
+			optionFuncs...,
+		)...,
+	); err != nil {
+		return coinbasecommerce.Charge{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Charge, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return charge, responseBody.Warnings, nil
 }
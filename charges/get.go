@@ -1,15 +1,14 @@
 package charges
 
 import (
-	"encoding/json"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	getOperation      = "charges.Get"
 	getEndpointMethod = "GET"
-	getEndpoint       = "https://api.commerce.coinbase.com/charges/"
+	getEndpointFmt    = "/charges/%s"
 )
 
 // Get retrieves a specific charge object using Coinbase Commerce API.
@@ -24,27 +23,17 @@ func Get(
 			}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var charge coinbasecommerce.Charge
+	responseBody := internal.APIResponse{Data: &charge}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		getOperation,
 		getEndpointMethod,
-		getEndpoint+idOrCode,
-	)
-	if err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Charge   coinbasecommerce.Charge    `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), getEndpointFmt, idOrCode),
+		&responseBody,
+	); err != nil {
+		return coinbasecommerce.Charge{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Charge, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return charge, responseBody.Warnings, nil
 }
@@ -0,0 +1,119 @@
+package charges
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/internal"
+)
+
+// IdempotencyKeyMetadataKey is the key of the metadata entry that records the
+// idempotency key of a charge.
+const IdempotencyKeyMetadataKey = "idempotency_key"
+
+// createIdempotent creates the charge unless a charge with its idempotency
+// key was already created. The charge is looked up before every attempt of its
+// creation, so that a creation whose response was lost, e.g. because of a
+// timeout, isn't repeated when it's retried.
+func createIdempotent(
+	apiCallContext coinbasecommerce.APICallContext,
+	request CreateRequest,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	metadata := make(map[string]string, len(request.Metadata)+1)
+	for key, value := range request.Metadata {
+		metadata[key] = value
+	}
+	metadata[IdempotencyKeyMetadataKey] = request.IdempotencyKey
+	request.Metadata = metadata
+
+	var existingCharge *coinbasecommerce.Charge
+	var existingWarnings coinbasecommerce.Warnings
+	check := func(ctx context.Context) (bool, error) {
+		// the lookups are API calls of their own, which are retried along
+		// with the creation instead of on their own, and which aren't
+		// described by the response metadata of the creation
+		lookupCallContext := apiCallContext.With(
+			coinbasecommerce.APICallContextOptionContext(ctx),
+			coinbasecommerce.APICallContextOptionRetryPolicy(nil),
+			coinbasecommerce.APICallContextOptionResponseMeta(nil),
+		)
+		charge, warnings, found, err := findIdempotent(ctx, lookupCallContext, request.IdempotencyKey)
+		if found {
+			existingCharge, existingWarnings = &charge, warnings
+		}
+		return found, err
+	}
+
+	charge, warnings, err := create(apiCallContext, request, internal.APIRequestOptionsIdempotent(check))
+	if err != nil {
+		return coinbasecommerce.Charge{}, warnings, err
+	} else if existingCharge != nil {
+		return *existingCharge, existingWarnings, nil
+	}
+
+	ctx := apiCallContext.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return charge, warnings, storeIdempotent(ctx, apiCallContext, request.IdempotencyKey, charge)
+}
+
+// findIdempotent looks up the charge that was created with the idempotency
+// key, first in the idempotency store and then among the most recently
+// created charges.
+func findIdempotent(
+	ctx context.Context,
+	apiCallContext coinbasecommerce.APICallContext,
+	idempotencyKey string,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, bool, error) {
+	if store := apiCallContext.IdempotencyStore(); store != nil {
+		id, ok, err := store.Load(ctx, idempotencyKey)
+		if err != nil {
+			return coinbasecommerce.Charge{}, nil, false,
+				coinbasecommerce.LocalError{Inner: err}
+		}
+		if ok {
+			charge, warnings, err := Get(apiCallContext, id)
+			if err == nil {
+				return charge, warnings, true, nil
+			} else if !coinbasecommerce.IsNotFound(err) {
+				return coinbasecommerce.Charge{}, nil, false, err
+			}
+		}
+	}
+
+	limit := apiCallContext.IdempotencyScanLimit()
+	if limit == 0 {
+		return coinbasecommerce.Charge{}, nil, false, nil
+	}
+	charges, _, warnings, err := List(
+		apiCallContext,
+		coinbasecommerce.NewPaginationOption(coinbasecommerce.PaginationOptionLimit(limit)),
+	)
+	if err != nil {
+		return coinbasecommerce.Charge{}, nil, false, err
+	}
+	for _, charge := range charges {
+		if charge.Metadata[IdempotencyKeyMetadataKey] == idempotencyKey {
+			err := storeIdempotent(ctx, apiCallContext, idempotencyKey, charge)
+			return charge, warnings, true, err
+		}
+	}
+	return coinbasecommerce.Charge{}, nil, false, nil
+}
+
+func storeIdempotent(
+	ctx context.Context,
+	apiCallContext coinbasecommerce.APICallContext,
+	idempotencyKey string,
+	charge coinbasecommerce.Charge,
+) error {
+	store := apiCallContext.IdempotencyStore()
+	if store == nil {
+		return nil
+	}
+	if err := store.Store(ctx, idempotencyKey, charge.ID); err != nil {
+		return coinbasecommerce.LocalError{Inner: err}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package charges_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+func TestCreateIdempotentTimeoutAfterCreation(t *testing.T) {
+	fake := cbctest.New()
+	var mu sync.Mutex
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			fake.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		posts++
+		first := posts == 1
+		mu.Unlock()
+		if !first {
+			fake.ServeHTTP(w, r)
+			return
+		}
+		// the charge is created, but the response is lost
+		fake.ServeHTTP(httptest.NewRecorder(), r)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	apiCallContext := newIdempotentAPICallContext(
+		coinbasecommerce.APIConfigOptionBaseURL(server.URL),
+		coinbasecommerce.APICallContextOptionHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}),
+	)
+	charge, _, err := charges.Create(apiCallContext, newCreateRequest("key-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if posts != 1 {
+		t.Errorf("POST requests = %d, want 1", posts)
+	}
+	created := fake.Charges()
+	if len(created) != 1 {
+		t.Fatalf("created charges = %d, want 1", len(created))
+	}
+	if charge.ID != created[0].ID {
+		t.Errorf("Create() charge ID = %s, want %s", charge.ID, created[0].ID)
+	}
+}
+
+func TestCreateIdempotentDuplicateKey(t *testing.T) {
+	fake := cbctest.NewServer()
+	defer fake.Close()
+
+	tests := []struct {
+		name       string
+		optionFunc coinbasecommerce.APICallContextOptionFunc
+	}{
+		{"idempotency store", coinbasecommerce.APICallContextOptionIdempotencyStore(coinbasecommerce.NewMemoryIdempotencyStore())},
+		{"scan of the recent charges", coinbasecommerce.APICallContextOptionIdempotencyScanLimit(25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "key-" + tt.name
+			apiCallContext := newIdempotentAPICallContext(
+				coinbasecommerce.APIConfigOptionBaseURL(fake.URL()), tt.optionFunc)
+
+			first, _, err := charges.Create(apiCallContext, newCreateRequest(key))
+			if err != nil {
+				t.Fatalf("first Create() error = %v", err)
+			}
+			created := len(fake.Charges())
+			second, _, err := charges.Create(apiCallContext, newCreateRequest(key))
+			if err != nil {
+				t.Fatalf("second Create() error = %v", err)
+			}
+			if second.ID != first.ID {
+				t.Errorf("second Create() charge ID = %s, want %s", second.ID, first.ID)
+			}
+			if got := len(fake.Charges()); got != created {
+				t.Errorf("charges created by the second Create() = %d, want 0", got-created)
+			}
+			if got := first.Metadata[charges.IdempotencyKeyMetadataKey]; got != key {
+				t.Errorf("metadata %s = %q, want %q", charges.IdempotencyKeyMetadataKey, got, key)
+			}
+		})
+	}
+}
+
+func TestCreateIdempotentScanLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantFound bool
+	}{
+		{"within the limit", 3, true},
+		{"beyond the limit", 2, false},
+		{"no scan", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := cbctest.NewServer()
+			defer fake.Close()
+			existing := fake.AddCharge(coinbasecommerce.Charge{
+				Name:     "Existing",
+				Metadata: map[string]string{charges.IdempotencyKeyMetadataKey: "key-1"},
+			})
+			fake.AddCharge(coinbasecommerce.Charge{Name: "Newer"})
+			fake.AddCharge(coinbasecommerce.Charge{Name: "Newest"})
+
+			apiCallContext := newIdempotentAPICallContext(
+				coinbasecommerce.APIConfigOptionBaseURL(fake.URL()),
+				coinbasecommerce.APICallContextOptionIdempotencyScanLimit(tt.limit),
+			)
+			charge, _, err := charges.Create(apiCallContext, newCreateRequest("key-1"))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if found := charge.ID == existing.ID; found != tt.wantFound {
+				t.Errorf("Create() returned the existing charge = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestCreateIdempotentRetries(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	apiCallContext := newIdempotentAPICallContext(coinbasecommerce.APIConfigOptionBaseURL(server.URL))
+	_, _, err := charges.Create(apiCallContext, newCreateRequest("key-1"))
+
+	var retryError coinbasecommerce.RetryError
+	if !errors.As(err, &retryError) || retryError.Attempts != 3 {
+		t.Fatalf("Create() error = %v, want a RetryError after 3 attempts", err)
+	}
+	if errors.As(retryError.Inner, &retryError) {
+		t.Errorf("Create() error = %v, want no nested RetryError", err)
+	}
+	if strings.Count(err.Error(), "failed after") != 1 {
+		t.Errorf("Create() error = %q, want the attempts to be counted once", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+}
+
+func TestCreateIdempotentResponseMeta(t *testing.T) {
+	fake := cbctest.NewServer()
+	defer fake.Close()
+	fake.AddCharge(coinbasecommerce.Charge{Name: "Another"})
+
+	var responseMeta coinbasecommerce.ResponseMeta
+	apiCallContext := newIdempotentAPICallContext(
+		coinbasecommerce.APIConfigOptionBaseURL(fake.URL()),
+		coinbasecommerce.APICallContextOptionResponseMeta(&responseMeta),
+	)
+	tests := []struct {
+		name           string
+		wantStatusCode int
+		wantAttempts   int
+	}{
+		{"created", http.StatusCreated, 1},
+		{"found", 0, 0},
+	}
+	for _, tt := range tests {
+		responseMeta = coinbasecommerce.ResponseMeta{}
+		charge, _, err := charges.Create(apiCallContext, newCreateRequest("key-1"))
+		if err != nil {
+			t.Fatalf("%s: Create() error = %v", tt.name, err)
+		}
+		if responseMeta.Operation != "charges.Create" {
+			t.Errorf("%s: Operation = %q, want %q", tt.name, responseMeta.Operation, "charges.Create")
+		}
+		if responseMeta.StatusCode != tt.wantStatusCode {
+			t.Errorf("%s: StatusCode = %d, want %d", tt.name, responseMeta.StatusCode, tt.wantStatusCode)
+		}
+		if responseMeta.Attempts != tt.wantAttempts {
+			t.Errorf("%s: Attempts = %d, want %d", tt.name, responseMeta.Attempts, tt.wantAttempts)
+		}
+		if tt.wantAttempts > 0 && !strings.Contains(string(responseMeta.RawBody), charge.ID) {
+			t.Errorf("%s: RawBody = %s, want the created charge", tt.name, responseMeta.RawBody)
+		}
+		if strings.Contains(string(responseMeta.RawBody), "Another") {
+			t.Errorf("%s: RawBody = %s, want no listed charges", tt.name, responseMeta.RawBody)
+		}
+	}
+}
+
+// newIdempotentAPICallContext creates an API call context with the API config
+// of the fake API, which retries the calls three times without waiting.
+func newIdempotentAPICallContext(
+	apiConfigOptionFunc coinbasecommerce.APIConfigOptionFunc,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) coinbasecommerce.APICallContext {
+	optionFuncs = append([]coinbasecommerce.APICallContextOptionFunc{
+		coinbasecommerce.APICallContextOptionRetryPolicy(coinbasecommerce.NewRetryPolicy(
+			coinbasecommerce.RetryPolicyOptionMaxAttempts(3),
+			coinbasecommerce.RetryPolicyOptionBackoff(0, 0),
+		)),
+	}, optionFuncs...)
+	return coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig(cbctest.APIKey, cbctest.APIVersion, apiConfigOptionFunc),
+		optionFuncs...,
+	)
+}
+
+// newCreateRequest creates a request to create a fixed price charge with the
+// idempotency key.
+func newCreateRequest(idempotencyKey string) charges.CreateRequest {
+	return charges.CreateRequest{
+		Name:           "Coffee",
+		Description:    "A cup of coffee",
+		PricingType:    coinbasecommerce.PricingTypeFixed,
+		LocalPrice:     &coinbasecommerce.Money{Amount: 2.5, Currency: "USD"},
+		IdempotencyKey: idempotencyKey,
+	}
+}
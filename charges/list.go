@@ -1,15 +1,14 @@
 package charges
 
 import (
-	"encoding/json"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	listOperation      = "charges.List"
 	listEndpointMethod = "GET"
-	listEndpoint       = "https://api.commerce.coinbase.com/charges?"
+	listEndpoint       = "/charges"
 )
 
 // List retrieves a list of charges from the Coinbase Commerce API.
@@ -22,28 +21,21 @@ func List(
 	coinbasecommerce.Warnings,
 	error,
 ) {
-	response, err := internal.CreateAndDoHTTPRequest(
+	var charges []coinbasecommerce.Charge
+	var pagination coinbasecommerce.Pagination
+	responseBody := internal.APIResponse{Data: &charges, Pagination: &pagination}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		listOperation,
 		listEndpointMethod,
-		listEndpoint+paginationOption.MakeQueryString(),
-	)
-	if err != nil {
-		return nil, coinbasecommerce.Pagination{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Pagination coinbasecommerce.Pagination `json:"pagination"`
-		Charges    []coinbasecommerce.Charge   `json:"data"`
-		Error      *coinbasecommerce.APIError  `json:"error,omitempty"`
-		Warnings   coinbasecommerce.Warnings   `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return nil, coinbasecommerce.Pagination{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.AppendQueryString(
+			internal.MakeEndpoint(apiCallContext.APIConfig(), listEndpoint),
+			paginationOption.MakeQueryString(),
+		),
+		&responseBody,
+	); err != nil {
+		return nil, coinbasecommerce.Pagination{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Charges, responseBody.Pagination, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return charges, pagination, responseBody.Warnings, nil
 }
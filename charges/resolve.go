@@ -1,16 +1,14 @@
 package charges
 
 import (
-	"encoding/json"
-	"fmt"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	resolveOperation      = "charges.Resolve"
 	resolveEndpointMethod = "POST"
-	resolveEndpointFmt    = "https://api.commerce.coinbase.com/charges/%s/resolve"
+	resolveEndpointFmt    = "/charges/%s/resolve"
 )
 
 // Resolve resolves a charge object using the Coinbase Commerce API.
@@ -25,27 +23,17 @@ func Resolve(
 			}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var charge coinbasecommerce.Charge
+	responseBody := internal.APIResponse{Data: &charge}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		resolveOperation,
 		resolveEndpointMethod,
-		fmt.Sprintf(resolveEndpointFmt, idOrCode),
-	)
-	if err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Charge   coinbasecommerce.Charge    `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Charge{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), resolveEndpointFmt, idOrCode),
+		&responseBody,
+	); err != nil {
+		return coinbasecommerce.Charge{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Charge, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return charge, responseBody.Warnings, nil
 }
@@ -25,8 +25,9 @@ type CreateRequest struct {
 }
 
 const (
+	createOperation      = "checkouts.Create"
 	createEndpointMethod = "POST"
-	createEndpoint       = "https://api.commerce.coinbase.com/checkouts"
+	createEndpoint       = "/checkouts"
 )
 
 // Create creates a checkout by sending a request to the Coinbase Commerce API.
@@ -41,28 +42,18 @@ func Create(
 			coinbasecommerce.LocalError{Inner: err}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var checkout coinbasecommerce.Checkout
+	responseBody := internal.APIResponse{Data: &checkout}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		createOperation,
 		createEndpointMethod,
-		createEndpoint,
-		internal.CreateAndDoHTTPRequestOptionsJSONBody(bodyBuffer),
-	)
-	if err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Checkout coinbasecommerce.Checkout  `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), createEndpoint),
+		&responseBody,
+		internal.APIRequestOptionsJSONBody(bodyBuffer.Bytes()),
+	); err != nil {
+		return coinbasecommerce.Checkout{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Checkout, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return checkout, responseBody.Warnings, nil
 }
@@ -1,16 +1,14 @@
 package checkouts
 
 import (
-	"encoding/json"
-	"fmt"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	deleteOperation      = "checkouts.Delete"
 	deleteEndpointMethod = "DELETE"
-	deleteEndpointFmt    = "https://api.commerce.coinbase.com/checkouts/%s"
+	deleteEndpointFmt    = "/checkouts/%s"
 )
 
 // Delete deletes a checkout object using the Coinbase Commerce API.
@@ -24,23 +22,14 @@ func Delete(
 		}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var responseBody internal.APIResponse
+	err := internal.DoAPIRequest(
 		apiCallContext,
+		deleteOperation,
 		deleteEndpointMethod,
-		fmt.Sprintf(deleteEndpointFmt, id),
+		internal.MakeEndpoint(apiCallContext.APIConfig(), deleteEndpointFmt, id),
+		&responseBody,
 	)
-	if err != nil {
-		return nil, coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return nil, coinbasecommerce.LocalError{Inner: err}
-	}
 
-	return responseBody.Warnings, coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return responseBody.Warnings, err
 }
@@ -1,15 +1,14 @@
 package checkouts
 
 import (
-	"encoding/json"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	getOperation      = "checkouts.Get"
 	getEndpointMethod = "GET"
-	getEndpoint       = "https://api.commerce.coinbase.com/checkouts/"
+	getEndpointFmt    = "/checkouts/%s"
 )
 
 // Get retrieves a specific checkout object using Coinbase Commerce API.
@@ -23,27 +22,17 @@ func Get(
 			}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var checkout coinbasecommerce.Checkout
+	responseBody := internal.APIResponse{Data: &checkout}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		getOperation,
 		getEndpointMethod,
-		getEndpoint+id,
-	)
-	if err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Checkout coinbasecommerce.Checkout  `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), getEndpointFmt, id),
+		&responseBody,
+	); err != nil {
+		return coinbasecommerce.Checkout{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Checkout, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return checkout, responseBody.Warnings, nil
 }
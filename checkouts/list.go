@@ -1,15 +1,14 @@
 package checkouts
 
 import (
-	"encoding/json"
-
 	"github.com/bmdelacruz/coinbasecommerce"
 	"github.com/bmdelacruz/coinbasecommerce/internal"
 )
 
 const (
+	listOperation      = "checkouts.List"
 	listEndpointMethod = "GET"
-	listEndpoint       = "https://api.commerce.coinbase.com/checkouts?"
+	listEndpoint       = "/checkouts"
 )
 
 // List retrieves a list of checkouts from the Coinbase Commerce API.
@@ -22,28 +21,21 @@ func List(
 	coinbasecommerce.Warnings,
 	error,
 ) {
-	response, err := internal.CreateAndDoHTTPRequest(
+	var checkouts []coinbasecommerce.Checkout
+	var pagination coinbasecommerce.Pagination
+	responseBody := internal.APIResponse{Data: &checkouts, Pagination: &pagination}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		listOperation,
 		listEndpointMethod,
-		listEndpoint+paginationOption.MakeQueryString(),
-	)
-	if err != nil {
-		return nil, coinbasecommerce.Pagination{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Pagination coinbasecommerce.Pagination `json:"pagination"`
-		Checkouts  []coinbasecommerce.Checkout `json:"data"`
-		Error      *coinbasecommerce.APIError  `json:"error,omitempty"`
-		Warnings   coinbasecommerce.Warnings   `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return nil, coinbasecommerce.Pagination{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.AppendQueryString(
+			internal.MakeEndpoint(apiCallContext.APIConfig(), listEndpoint),
+			paginationOption.MakeQueryString(),
+		),
+		&responseBody,
+	); err != nil {
+		return nil, coinbasecommerce.Pagination{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Checkouts, responseBody.Pagination, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return checkouts, pagination, responseBody.Warnings, nil
 }
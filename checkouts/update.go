@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 
 	"github.com/bmdelacruz/coinbasecommerce"
@@ -103,8 +102,9 @@ func (options *UpdateOptions) WriteJSON(w io.Writer) error {
 }
 
 const (
+	updateOperation      = "checkouts.Update"
 	updateEndpointMethod = "PUT"
-	updateEndpointFmt    = "https://api.commerce.coinbase.com/checkouts/%s"
+	updateEndpointFmt    = "/checkouts/%s"
 )
 
 // Errors related to Update function
@@ -113,7 +113,8 @@ var (
 )
 
 // Update updates the fields of a checkout object with matching id using the Coinbase
-// Commerce API
+// Commerce API. Since the fields are set to the same values whenever it's sent,
+// it's retried according to the retry policy of the API call context.
 func Update(
 	apiCallContext coinbasecommerce.APICallContext,
 	id string,
@@ -137,28 +138,19 @@ func Update(
 			coinbasecommerce.LocalError{Inner: err}
 	}
 
-	response, err := internal.CreateAndDoHTTPRequest(
+	var checkout coinbasecommerce.Checkout
+	responseBody := internal.APIResponse{Data: &checkout}
+	if err := internal.DoAPIRequest(
 		apiCallContext,
+		updateOperation,
 		updateEndpointMethod,
-		fmt.Sprintf(updateEndpointFmt, id),
-		internal.CreateAndDoHTTPRequestOptionsJSONBody(bodyBuffer),
-	)
-	if err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
-	}
-	defer response.Body.Close()
-
-	var responseBody struct {
-		Checkout coinbasecommerce.Checkout  `json:"data"`
-		Error    *coinbasecommerce.APIError `json:"error,omitempty"`
-		Warnings coinbasecommerce.Warnings  `json:"warnings"`
-	}
-	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
-		return coinbasecommerce.Checkout{}, nil,
-			coinbasecommerce.LocalError{Inner: err}
+		internal.MakeEndpoint(apiCallContext.APIConfig(), updateEndpointFmt, id),
+		&responseBody,
+		internal.APIRequestOptionsJSONBody(bodyBuffer.Bytes()),
+		internal.APIRequestOptionsIdempotent(nil),
+	); err != nil {
+		return coinbasecommerce.Checkout{}, responseBody.Warnings, err
 	}
 
-	return responseBody.Checkout, responseBody.Warnings,
-		coinbasecommerce.ReturnAPIErrorAsError(responseBody.Error)
+	return checkout, responseBody.Warnings, nil
 }
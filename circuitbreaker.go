@@ -0,0 +1,269 @@
+package coinbasecommerce
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState represents the state of a circuit breaker.
+type CircuitState int
+
+// CircuitState constants.
+const (
+	// CircuitStateClosed lets every request through.
+	CircuitStateClosed CircuitState = iota
+	// CircuitStateOpen rejects every request with ErrCircuitOpen.
+	CircuitStateOpen
+	// CircuitStateHalfOpen lets a limited number of probe requests through
+	// to find out if the API has recovered.
+	CircuitStateHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitStateClosed:
+		return "closed"
+	case CircuitStateOpen:
+		return "open"
+	case CircuitStateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitGeneration identifies a period in which the state of a circuit
+// didn't change. The outcomes of the requests are only counted in the
+// generation that they were allowed in, so that a request that was allowed
+// while the circuit was closed, and finished after it became half-open, isn't
+// mistaken for a probe.
+type CircuitGeneration uint64
+
+// CircuitBreaker stops the requests to the Coinbase Commerce API for a while
+// after too many of them have failed in a row, i.e. they couldn't be sent or
+// they received a 5xx response. It's safe for concurrent use by multiple
+// goroutines.
+type CircuitBreaker struct {
+	failureThreshold int
+	successThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	onStateChange    func(from, to CircuitState)
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation CircuitGeneration
+	failures   int
+	successes  int
+	probes     int
+	openedAt   time.Time
+}
+
+// CircuitBreakerOptionFunc represents a function that can modify the contents
+// of the CircuitBreaker before it's used.
+type CircuitBreakerOptionFunc func(*CircuitBreaker)
+
+// CircuitBreakerOptionFailureThreshold sets the number of consecutive failed
+// requests that opens the circuit.
+func CircuitBreakerOptionFailureThreshold(failureThreshold int) CircuitBreakerOptionFunc {
+	if failureThreshold < 1 {
+		panic(`invalid circuit breaker failure threshold. valid values: failureThreshold >= 1`)
+	}
+	return func(breaker *CircuitBreaker) {
+		breaker.failureThreshold = failureThreshold
+	}
+}
+
+// CircuitBreakerOptionSuccessThreshold sets the number of successful probe
+// requests that closes a half-open circuit.
+func CircuitBreakerOptionSuccessThreshold(successThreshold int) CircuitBreakerOptionFunc {
+	if successThreshold < 1 {
+		panic(`invalid circuit breaker success threshold. valid values: successThreshold >= 1`)
+	}
+	return func(breaker *CircuitBreaker) {
+		breaker.successThreshold = successThreshold
+	}
+}
+
+// CircuitBreakerOptionOpenTimeout sets how long the circuit stays open before
+// it becomes half-open.
+func CircuitBreakerOptionOpenTimeout(openTimeout time.Duration) CircuitBreakerOptionFunc {
+	if openTimeout <= 0 {
+		panic(`invalid circuit breaker open timeout. valid values: openTimeout > 0`)
+	}
+	return func(breaker *CircuitBreaker) {
+		breaker.openTimeout = openTimeout
+	}
+}
+
+// CircuitBreakerOptionHalfOpenProbes sets the maximum number of concurrent
+// probe requests that are let through while the circuit is half-open.
+func CircuitBreakerOptionHalfOpenProbes(halfOpenProbes int) CircuitBreakerOptionFunc {
+	if halfOpenProbes < 1 {
+		panic(`invalid circuit breaker half-open probes. valid values: halfOpenProbes >= 1`)
+	}
+	return func(breaker *CircuitBreaker) {
+		breaker.halfOpenProbes = halfOpenProbes
+	}
+}
+
+// CircuitBreakerOptionOnStateChange sets the function that's called after
+// the state of the circuit has changed, e.g. to show that crypto payments
+// are temporarily unavailable. It must not block.
+func CircuitBreakerOptionOnStateChange(onStateChange func(from, to CircuitState)) CircuitBreakerOptionFunc {
+	return func(breaker *CircuitBreaker) {
+		breaker.onStateChange = onStateChange
+	}
+}
+
+// NewCircuitBreaker creates a new circuit breaker. By default, the circuit
+// opens after 5 consecutive failures, becomes half-open after 30s, lets 1
+// probe request through, and closes after 1 successful probe.
+func NewCircuitBreaker(optionFuncs ...CircuitBreakerOptionFunc) *CircuitBreaker {
+	breaker := CircuitBreaker{
+		failureThreshold: 5,
+		successThreshold: 1,
+		openTimeout:      30 * time.Second,
+		halfOpenProbes:   1,
+		onStateChange:    nil,
+		state:            CircuitStateClosed,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&breaker)
+	}
+	return &breaker
+}
+
+// State returns the current state of the circuit.
+func (breaker *CircuitBreaker) State() CircuitState {
+	var transitions []CircuitState
+
+	breaker.mu.Lock()
+	breaker.halfOpenIfTimedOut(&transitions)
+	state := breaker.state
+	breaker.mu.Unlock()
+
+	breaker.notify(transitions)
+	return state
+}
+
+// Allow returns ErrCircuitOpen if a request must not be sent. Otherwise, the
+// outcome of the request must be reported using RecordSuccess, RecordFailure
+// or RecordCanceled, along with the generation that Allow returned.
+func (breaker *CircuitBreaker) Allow() (CircuitGeneration, error) {
+	var transitions []CircuitState
+
+	breaker.mu.Lock()
+	breaker.halfOpenIfTimedOut(&transitions)
+	generation := breaker.generation
+	var err error
+	switch breaker.state {
+	case CircuitStateOpen:
+		err = ErrCircuitOpen
+	case CircuitStateHalfOpen:
+		if breaker.probes >= breaker.halfOpenProbes {
+			err = ErrCircuitOpen
+		} else {
+			breaker.probes++
+		}
+	}
+	breaker.mu.Unlock()
+
+	breaker.notify(transitions)
+	return generation, err
+}
+
+// RecordSuccess reports that a request that was allowed in the generation
+// has succeeded. It's ignored if the state of the circuit has changed since.
+func (breaker *CircuitBreaker) RecordSuccess(generation CircuitGeneration) {
+	var transitions []CircuitState
+
+	breaker.mu.Lock()
+	if generation == breaker.generation {
+		switch breaker.state {
+		case CircuitStateClosed:
+			breaker.failures = 0
+		case CircuitStateHalfOpen:
+			breaker.releaseProbe()
+			breaker.successes++
+			if breaker.successes >= breaker.successThreshold {
+				breaker.setState(CircuitStateClosed, &transitions)
+			}
+		}
+	}
+	breaker.mu.Unlock()
+
+	breaker.notify(transitions)
+}
+
+// RecordFailure reports that a request that was allowed in the generation
+// has failed. It's ignored if the state of the circuit has changed since.
+func (breaker *CircuitBreaker) RecordFailure(generation CircuitGeneration) {
+	var transitions []CircuitState
+
+	breaker.mu.Lock()
+	if generation == breaker.generation {
+		switch breaker.state {
+		case CircuitStateClosed:
+			breaker.failures++
+			if breaker.failures >= breaker.failureThreshold {
+				breaker.setState(CircuitStateOpen, &transitions)
+			}
+		case CircuitStateHalfOpen:
+			breaker.releaseProbe()
+			breaker.setState(CircuitStateOpen, &transitions)
+		}
+	}
+	breaker.mu.Unlock()
+
+	breaker.notify(transitions)
+}
+
+// RecordCanceled reports that a request that was allowed in the generation
+// was canceled by its caller, which says nothing about the health of the API.
+func (breaker *CircuitBreaker) RecordCanceled(generation CircuitGeneration) {
+	breaker.mu.Lock()
+	if generation == breaker.generation && breaker.state == CircuitStateHalfOpen {
+		breaker.releaseProbe()
+	}
+	breaker.mu.Unlock()
+}
+
+func (breaker *CircuitBreaker) releaseProbe() {
+	if breaker.probes > 0 {
+		breaker.probes--
+	}
+}
+
+func (breaker *CircuitBreaker) halfOpenIfTimedOut(transitions *[]CircuitState) {
+	if breaker.state == CircuitStateOpen && time.Since(breaker.openedAt) >= breaker.openTimeout {
+		breaker.setState(CircuitStateHalfOpen, transitions)
+	}
+}
+
+// setState changes the state of the circuit and appends the previous and the
+// new state to transitions.
+func (breaker *CircuitBreaker) setState(state CircuitState, transitions *[]CircuitState) {
+	if breaker.state == state {
+		return
+	}
+	*transitions = append(*transitions, breaker.state, state)
+
+	breaker.state = state
+	breaker.generation++
+	breaker.failures = 0
+	breaker.successes = 0
+	breaker.probes = 0
+	if state == CircuitStateOpen {
+		breaker.openedAt = time.Now()
+	}
+}
+
+func (breaker *CircuitBreaker) notify(transitions []CircuitState) {
+	if breaker.onStateChange == nil {
+		return
+	}
+	for i := 0; i+1 < len(transitions); i += 2 {
+		breaker.onStateChange(transitions[i], transitions[i+1])
+	}
+}
@@ -0,0 +1,288 @@
+package coinbasecommerce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+const testOpenTimeout = 20 * time.Millisecond
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	// each step allows a request and then records its outcome, or waits for
+	// the circuit to become half-open
+	type step struct {
+		outcome string // "success", "failure", "canceled", "rejected" or "wait"
+		state   coinbasecommerce.CircuitState
+	}
+	closed := coinbasecommerce.CircuitStateClosed
+	open := coinbasecommerce.CircuitStateOpen
+	halfOpen := coinbasecommerce.CircuitStateHalfOpen
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "opens after consecutive failures",
+			steps: []step{{"failure", closed}, {"failure", closed}, {"failure", open}, {"rejected", open}},
+		},
+		{
+			name:  "success resets the failures",
+			steps: []step{{"failure", closed}, {"failure", closed}, {"success", closed}, {"failure", closed}},
+		},
+		{
+			name: "closes after a successful probe",
+			steps: []step{
+				{"failure", closed}, {"failure", closed}, {"failure", open},
+				{"wait", halfOpen}, {"success", closed},
+			},
+		},
+		{
+			name: "reopens after a failed probe",
+			steps: []step{
+				{"failure", closed}, {"failure", closed}, {"failure", open},
+				{"wait", halfOpen}, {"failure", open}, {"rejected", open},
+			},
+		},
+		{
+			name: "canceled probe releases its slot",
+			steps: []step{
+				{"failure", closed}, {"failure", closed}, {"failure", open},
+				{"wait", halfOpen}, {"canceled", halfOpen}, {"success", closed},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker := coinbasecommerce.NewCircuitBreaker(
+				coinbasecommerce.CircuitBreakerOptionFailureThreshold(3),
+				coinbasecommerce.CircuitBreakerOptionOpenTimeout(testOpenTimeout),
+			)
+			for i, step := range test.steps {
+				if step.outcome == "wait" {
+					time.Sleep(testOpenTimeout)
+				} else {
+					generation, err := breaker.Allow()
+					switch {
+					case step.outcome == "rejected":
+						if !errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+							t.Fatalf("step %d: Allow() = %v, want %v", i, err, coinbasecommerce.ErrCircuitOpen)
+						}
+					case err != nil:
+						t.Fatalf("step %d: Allow() = %v", i, err)
+					case step.outcome == "success":
+						breaker.RecordSuccess(generation)
+					case step.outcome == "failure":
+						breaker.RecordFailure(generation)
+					case step.outcome == "canceled":
+						breaker.RecordCanceled(generation)
+					}
+				}
+				if state := breaker.State(); state != step.state {
+					t.Fatalf("step %d: State() = %s, want %s", i, state, step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	breaker := coinbasecommerce.NewCircuitBreaker(
+		coinbasecommerce.CircuitBreakerOptionFailureThreshold(1),
+		coinbasecommerce.CircuitBreakerOptionOpenTimeout(testOpenTimeout),
+		coinbasecommerce.CircuitBreakerOptionHalfOpenProbes(2),
+		coinbasecommerce.CircuitBreakerOptionSuccessThreshold(2),
+	)
+	generation, _ := breaker.Allow()
+	breaker.RecordFailure(generation)
+	time.Sleep(testOpenTimeout)
+
+	first, err := breaker.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := breaker.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := breaker.Allow(); !errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+		t.Fatalf("third probe: Allow() = %v, want %v", err, coinbasecommerce.ErrCircuitOpen)
+	}
+
+	breaker.RecordSuccess(first)
+	if state := breaker.State(); state != coinbasecommerce.CircuitStateHalfOpen {
+		t.Fatalf("State() = %s after one successful probe, want half-open", state)
+	}
+	breaker.RecordSuccess(second)
+	if state := breaker.State(); state != coinbasecommerce.CircuitStateClosed {
+		t.Fatalf("State() = %s after two successful probes, want closed", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+	breaker := coinbasecommerce.NewCircuitBreaker(
+		coinbasecommerce.CircuitBreakerOptionFailureThreshold(1),
+		coinbasecommerce.CircuitBreakerOptionOpenTimeout(testOpenTimeout),
+	)
+
+	// allowed while closed, finishes after the circuit becomes half-open
+	slowSuccess, _ := breaker.Allow()
+	slowFailure, _ := breaker.Allow()
+	failure, _ := breaker.Allow()
+	breaker.RecordFailure(failure)
+	time.Sleep(testOpenTimeout)
+
+	probe, err := breaker.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker.RecordSuccess(slowSuccess)
+	breaker.RecordFailure(slowFailure)
+	if state := breaker.State(); state != coinbasecommerce.CircuitStateHalfOpen {
+		t.Fatalf("State() = %s after stale outcomes, want half-open", state)
+	}
+	if _, err := breaker.Allow(); !errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+		t.Fatalf("Allow() = %v while the probe is in flight, want %v", err, coinbasecommerce.ErrCircuitOpen)
+	}
+
+	breaker.RecordSuccess(probe)
+	if state := breaker.State(); state != coinbasecommerce.CircuitStateClosed {
+		t.Fatalf("State() = %s after the probe succeeded, want closed", state)
+	}
+}
+
+func TestCircuitBreakerOnStateChange(t *testing.T) {
+	var transitions [][2]coinbasecommerce.CircuitState
+	breaker := coinbasecommerce.NewCircuitBreaker(
+		coinbasecommerce.CircuitBreakerOptionFailureThreshold(1),
+		coinbasecommerce.CircuitBreakerOptionOpenTimeout(testOpenTimeout),
+		coinbasecommerce.CircuitBreakerOptionOnStateChange(func(from, to coinbasecommerce.CircuitState) {
+			transitions = append(transitions, [2]coinbasecommerce.CircuitState{from, to})
+		}),
+	)
+	generation, _ := breaker.Allow()
+	breaker.RecordFailure(generation)
+	time.Sleep(testOpenTimeout)
+	generation, _ = breaker.Allow()
+	breaker.RecordSuccess(generation)
+
+	want := [][2]coinbasecommerce.CircuitState{
+		{coinbasecommerce.CircuitStateClosed, coinbasecommerce.CircuitStateOpen},
+		{coinbasecommerce.CircuitStateOpen, coinbasecommerce.CircuitStateHalfOpen},
+		{coinbasecommerce.CircuitStateHalfOpen, coinbasecommerce.CircuitStateClosed},
+	}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+}
+
+func TestCircuitBreakerConcurrentGenerations(t *testing.T) {
+	// the charges "SLOW" and "PROBE" are only returned after they're released
+	arrived := make(chan string, 2)
+	release := map[string]chan struct{}{"SLOW": make(chan struct{}), "PROBE": make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := path.Base(r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if code == "FAIL" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": {"type": "internal_server_error", "message": "Oops"}}`))
+			return
+		}
+		if gate, ok := release[code]; ok {
+			arrived <- code
+			<-gate
+		}
+		w.Write([]byte(`{"data": {"code": "` + code + `"}}`))
+	}))
+	defer server.Close()
+
+	breaker := coinbasecommerce.NewCircuitBreaker(
+		coinbasecommerce.CircuitBreakerOptionFailureThreshold(1),
+		coinbasecommerce.CircuitBreakerOptionOpenTimeout(testOpenTimeout),
+	)
+	// the interceptor sees the calls that the circuit breaker rejected too
+	var mu sync.Mutex
+	var rejected []string
+	apiCallContext := coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig("key", "2018-03-22",
+			coinbasecommerce.APIConfigOptionBaseURL(server.URL),
+			coinbasecommerce.APIConfigOptionCircuitBreaker(breaker),
+		),
+		coinbasecommerce.APICallContextOptionInterceptors(
+			func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+				err := next(ctx, call)
+				if errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+					if call.StatusCode != 0 {
+						t.Errorf("rejected call status code = %d, want 0", call.StatusCode)
+					}
+					mu.Lock()
+					rejected = append(rejected, path.Base(call.URL))
+					mu.Unlock()
+				}
+				return err
+			},
+		),
+	)
+	get := func(code string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			_, _, err := charges.Get(apiCallContext, code)
+			done <- err
+		}()
+		return done
+	}
+	assertState := func(step string, want coinbasecommerce.CircuitState) {
+		t.Helper()
+		if state := breaker.State(); state != want {
+			t.Fatalf("%s: State() = %s, want %s", step, state, want)
+		}
+	}
+
+	// allowed while the circuit is closed, finishes after it became half-open
+	slow := get("SLOW")
+	<-arrived
+	if err := <-get("FAIL"); !errors.Is(err, coinbasecommerce.ErrAPIInternalServerError) {
+		t.Fatalf("failed call error = %v, want %v", err, coinbasecommerce.ErrAPIInternalServerError)
+	}
+	assertState("after the failure", coinbasecommerce.CircuitStateOpen)
+	if err := <-get("OPEN"); !errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+		t.Fatalf("call while open error = %v, want %v", err, coinbasecommerce.ErrCircuitOpen)
+	}
+
+	time.Sleep(testOpenTimeout)
+	probe := get("PROBE")
+	<-arrived
+	close(release["SLOW"])
+	if err := <-slow; err != nil {
+		t.Fatalf("slow call error = %v", err)
+	}
+	assertState("after the slow call of the closed circuit", coinbasecommerce.CircuitStateHalfOpen)
+	if err := <-get("PROBING"); !errors.Is(err, coinbasecommerce.ErrCircuitOpen) {
+		t.Fatalf("call while probing error = %v, want %v", err, coinbasecommerce.ErrCircuitOpen)
+	}
+
+	close(release["PROBE"])
+	if err := <-probe; err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	assertState("after the probe", coinbasecommerce.CircuitStateClosed)
+	if err := <-get("CLOSED"); err != nil {
+		t.Fatalf("call after the probe error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"OPEN", "PROBING"}; !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected calls = %v, want %v", rejected, want)
+	}
+}
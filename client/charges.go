@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+// ChargesService contains the operations on the charge resource of the
+// Coinbase Commerce API.
+type ChargesService struct {
+	client *Client
+}
+
+// Create creates a charge. See charges.Create.
+func (s *ChargesService) Create(
+	ctx context.Context,
+	request charges.CreateRequest,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	return charges.Create(s.client.NewAPICallContext(ctx, optionFuncs...), request)
+}
+
+// Get retrieves a specific charge. See charges.Get.
+func (s *ChargesService) Get(
+	ctx context.Context,
+	idOrCode string,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	return charges.Get(s.client.NewAPICallContext(ctx, optionFuncs...), idOrCode)
+}
+
+// List retrieves a list of charges. See charges.List.
+func (s *ChargesService) List(
+	ctx context.Context,
+	paginationOption coinbasecommerce.PaginationOption,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (
+	[]coinbasecommerce.Charge,
+	coinbasecommerce.Pagination,
+	coinbasecommerce.Warnings,
+	error,
+) {
+	return charges.List(s.client.NewAPICallContext(ctx, optionFuncs...), paginationOption)
+}
+
+// Cancel cancels a charge. See charges.Cancel.
+func (s *ChargesService) Cancel(
+	ctx context.Context,
+	idOrCode string,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	return charges.Cancel(s.client.NewAPICallContext(ctx, optionFuncs...), idOrCode)
+}
+
+// Resolve resolves a charge. See charges.Resolve.
+func (s *ChargesService) Resolve(
+	ctx context.Context,
+	idOrCode string,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Charge, coinbasecommerce.Warnings, error) {
+	return charges.Resolve(s.client.NewAPICallContext(ctx, optionFuncs...), idOrCode)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/checkouts"
+)
+
+// CheckoutsService contains the operations on the checkout resource of the
+// Coinbase Commerce API.
+type CheckoutsService struct {
+	client *Client
+}
+
+// Create creates a checkout. See checkouts.Create.
+func (s *CheckoutsService) Create(
+	ctx context.Context,
+	request checkouts.CreateRequest,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Checkout, coinbasecommerce.Warnings, error) {
+	return checkouts.Create(s.client.NewAPICallContext(ctx, optionFuncs...), request)
+}
+
+// Get retrieves a specific checkout. See checkouts.Get.
+func (s *CheckoutsService) Get(
+	ctx context.Context,
+	id string,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Checkout, coinbasecommerce.Warnings, error) {
+	return checkouts.Get(s.client.NewAPICallContext(ctx, optionFuncs...), id)
+}
+
+// List retrieves a list of checkouts. See checkouts.List.
+func (s *CheckoutsService) List(
+	ctx context.Context,
+	paginationOption coinbasecommerce.PaginationOption,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (
+	[]coinbasecommerce.Checkout,
+	coinbasecommerce.Pagination,
+	coinbasecommerce.Warnings,
+	error,
+) {
+	return checkouts.List(s.client.NewAPICallContext(ctx, optionFuncs...), paginationOption)
+}
+
+// Update updates the fields of a checkout. See checkouts.Update.
+func (s *CheckoutsService) Update(
+	ctx context.Context,
+	id string,
+	options checkouts.UpdateOptions,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Checkout, coinbasecommerce.Warnings, error) {
+	return checkouts.Update(s.client.NewAPICallContext(ctx, optionFuncs...), id, options)
+}
+
+// Delete deletes a checkout. See checkouts.Delete.
+func (s *CheckoutsService) Delete(
+	ctx context.Context,
+	id string,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) (coinbasecommerce.Warnings, error) {
+	return checkouts.Delete(s.client.NewAPICallContext(ctx, optionFuncs...), id)
+}
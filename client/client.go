@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Client is a long-lived client of the Coinbase Commerce API. It owns the API
+// configuration and the default options of every API call, and exposes the
+// resources of the API as services. A Client is safe for concurrent use by
+// multiple goroutines.
+type Client struct {
+	// Charges contains the operations on the charge resource.
+	Charges *ChargesService
+	// Checkouts contains the operations on the checkout resource.
+	Checkouts *CheckoutsService
+
+	apiConfig   *coinbasecommerce.APIConfig
+	optionFuncs []coinbasecommerce.APICallContextOptionFunc
+}
+
+// New creates a new client. The option functions are applied to every API
+// call that's made using the client, before the options of the call itself.
+func New(
+	apiConfig *coinbasecommerce.APIConfig,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) *Client {
+	if apiConfig == nil {
+		panic("apiConfig cannot be equal to nil")
+	}
+
+	client := &Client{
+		apiConfig: apiConfig,
+		optionFuncs: append(
+			[]coinbasecommerce.APICallContextOptionFunc(nil),
+			optionFuncs...,
+		),
+	}
+	client.Charges = &ChargesService{client: client}
+	client.Checkouts = &CheckoutsService{client: client}
+	return client
+}
+
+// APIConfig returns the API configuration object that's used by the client.
+func (c *Client) APIConfig() *coinbasecommerce.APIConfig {
+	return c.apiConfig
+}
+
+// NewAPICallContext creates an API call context that uses the configuration
+// and default options of the client, the context, and the call options.
+func (c *Client) NewAPICallContext(
+	ctx context.Context,
+	optionFuncs ...coinbasecommerce.APICallContextOptionFunc,
+) coinbasecommerce.APICallContext {
+	allOptionFuncs := make(
+		[]coinbasecommerce.APICallContextOptionFunc, 0,
+		len(c.optionFuncs)+len(optionFuncs)+1,
+	)
+	allOptionFuncs = append(allOptionFuncs, c.optionFuncs...)
+	allOptionFuncs = append(allOptionFuncs, coinbasecommerce.APICallContextOptionContext(ctx))
+	allOptionFuncs = append(allOptionFuncs, optionFuncs...)
+
+	return coinbasecommerce.NewAPICallContext(c.apiConfig, allOptionFuncs...)
+}
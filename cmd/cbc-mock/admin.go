@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+const adminPath = "/_admin/"
+
+// adminHandler lets the payments of the charges be simulated. It doesn't
+// check the API key.
+type adminHandler struct {
+	server *cbctest.Server
+}
+
+type simulateRequest struct {
+	Scenario cbctest.Scenario        `json:"scenario"`
+	Network  string                  `json:"network"`
+	Amount   *coinbasecommerce.Money `json:"amount"`
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminPath), "/"), "/")
+
+	switch {
+	case len(segments) == 1 && segments[0] == "charges" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, h.server.Charges())
+	case len(segments) == 3 && segments[0] == "charges" && segments[2] == "simulate" && r.Method == http.MethodPost:
+		h.simulate(w, r, segments[1])
+	case len(segments) == 1 && segments[0] == "webhooks" && r.Method == http.MethodGet:
+		type delivery struct {
+			Event      coinbasecommerce.Event `json:"event"`
+			StatusCode int                    `json:"status_code"`
+			Error      string                 `json:"error,omitempty"`
+		}
+		deliveries := []delivery{}
+		for _, d := range h.server.WebhookDeliveries() {
+			deliveries = append(deliveries, delivery{Event: d.Event, StatusCode: d.StatusCode})
+			if d.Err != nil {
+				deliveries[len(deliveries)-1].Error = d.Err.Error()
+			}
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (h *adminHandler) simulate(w http.ResponseWriter, r *http.Request, code string) {
+	request := simulateRequest{Scenario: cbctest.Scenario(r.URL.Query().Get("scenario"))}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	charge, err := simulate(h.server, code, request)
+	switch {
+	case errors.Is(err, cbctest.ErrChargeNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, charge)
+	}
+}
+
+// errInvalidNetwork is returned when a payment is simulated on an unknown
+// network.
+var errInvalidNetwork = errors.New("invalid network")
+
+func simulate(server *cbctest.Server, code string, request simulateRequest) (coinbasecommerce.Charge, error) {
+	var optionFuncs []cbctest.SimulateOptionFunc
+	if request.Network != "" {
+		valid := false
+		for _, network := range cbctest.Networks() {
+			valid = valid || network == request.Network
+		}
+		if !valid {
+			return coinbasecommerce.Charge{}, errInvalidNetwork
+		}
+		optionFuncs = append(optionFuncs, cbctest.SimulateOptionNetwork(request.Network))
+	}
+	if request.Amount != nil {
+		optionFuncs = append(optionFuncs, cbctest.SimulateOptionAmount(*request.Amount))
+	}
+	return server.Simulate(code, request.Scenario, optionFuncs...)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
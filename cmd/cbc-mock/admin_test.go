@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+func TestAdminSimulate(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		query          string
+		body           string
+		wantStatusCode int
+		wantStatus     coinbasecommerce.ChargeStatus
+		wantNetwork    string
+	}{
+		{
+			name:           "scenario in the body",
+			body:           `{"scenario": "underpaid", "network": "bitcoin"}`,
+			wantStatusCode: http.StatusOK,
+			wantStatus:     coinbasecommerce.ChargeStatusUnresolved,
+			wantNetwork:    "bitcoin",
+		},
+		{
+			name:           "scenario in the query",
+			query:          "?scenario=completed",
+			wantStatusCode: http.StatusOK,
+			wantStatus:     coinbasecommerce.ChargeStatusCompleted,
+			wantNetwork:    "ethereum",
+		},
+		{
+			name:           "amount in the body",
+			body:           `{"scenario": "pending", "amount": {"amount": "7.50", "currency": "USD"}}`,
+			wantStatusCode: http.StatusOK,
+			wantStatus:     coinbasecommerce.ChargeStatusPending,
+			wantNetwork:    "ethereum",
+		},
+		{name: "unknown charge", code: "MISSING", query: "?scenario=completed", wantStatusCode: http.StatusNotFound},
+		{name: "unknown network", body: `{"scenario": "pending", "network": "dogecoin"}`, wantStatusCode: http.StatusBadRequest},
+		{name: "unknown scenario", body: `{"scenario": "refunded"}`, wantStatusCode: http.StatusBadRequest},
+		{name: "invalid JSON", body: `{"scenario":`, wantStatusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cbctest.New()
+			code := server.AddCharge(coinbasecommerce.Charge{
+				Pricing: map[string]coinbasecommerce.Money{"local": {Amount: 30, Currency: "USD"}},
+			}).Code
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			response := httptest.NewRecorder()
+			(&adminHandler{server: server}).ServeHTTP(response, httptest.NewRequest(http.MethodPost,
+				adminPath+"charges/"+code+"/simulate"+tt.query, strings.NewReader(tt.body)))
+			if response.Code != tt.wantStatusCode {
+				t.Fatalf("status code = %d, want %d: %s", response.Code, tt.wantStatusCode, response.Body)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				var body struct{ Error string }
+				if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil || body.Error == "" {
+					t.Errorf("body = %s, want an error", response.Body)
+				}
+				return
+			}
+
+			var charge struct {
+				Timeline []coinbasecommerce.ChargeStatusUpdate `json:"timeline"`
+				Payments []cbctest.Payment                     `json:"payments"`
+			}
+			if err := json.Unmarshal(response.Body.Bytes(), &charge); err != nil {
+				t.Fatal(err)
+			}
+			if status := charge.Timeline[len(charge.Timeline)-1].Status; status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if len(charge.Payments) != 1 || charge.Payments[0].Network != tt.wantNetwork {
+				t.Errorf("payments = %+v, want one on %s", charge.Payments, tt.wantNetwork)
+			}
+		})
+	}
+}
+
+func TestAdminWebhookDeliveries(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer webhook.Close()
+	server := cbctest.New(cbctest.OptionWebhook(webhook.URL, "secret"))
+	code := server.AddCharge(coinbasecommerce.Charge{}).Code
+	if _, err := server.Simulate(code, cbctest.ScenarioExpired); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	(&adminHandler{server: server}).ServeHTTP(response, httptest.NewRequest(http.MethodGet, adminPath+"webhooks", nil))
+	var deliveries []struct {
+		Event      coinbasecommerce.Event `json:"event"`
+		StatusCode int                    `json:"status_code"`
+		Error      string                 `json:"error"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("deliveries = %+v, want 1", deliveries)
+	}
+	delivery := deliveries[0]
+	if delivery.Event.Type != coinbasecommerce.EventTypeChargeFailed ||
+		delivery.StatusCode != http.StatusServiceUnavailable || delivery.Error == "" {
+		t.Errorf("delivery = %+v, want a failed delivery of %s", delivery, coinbasecommerce.EventTypeChargeFailed)
+	}
+	if charge, ok := delivery.Event.Charge(); !ok || charge.Code != code {
+		t.Errorf("delivered event data = %+v, want the charge %s", delivery.Event.Data, code)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+const hostedPagePath = "/pay/"
+
+// hostedPageHandler serves a minimal version of the page that the customers
+// pay for a charge on, where the payment scenarios can be clicked through.
+type hostedPageHandler struct {
+	server *cbctest.Server
+}
+
+type hostedPage struct {
+	Charge      coinbasecommerce.Charge
+	Status      coinbasecommerce.ChargeStatus
+	Prices      []hostedPagePrice
+	Scenarios   []cbctest.Scenario
+	RedirectURL string
+	CancelURL   string
+	Error       string
+}
+
+type hostedPagePrice struct {
+	Network string
+	Price   coinbasecommerce.Money
+}
+
+func (h *hostedPageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code := strings.Trim(strings.TrimPrefix(r.URL.Path, hostedPagePath), "/")
+	charge, ok := h.server.Charge(code)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		// simulate the scenario, and then show the page again
+		location := hostedPagePath + url.PathEscape(charge.Code)
+		_, err := simulate(h.server, charge.Code, simulateRequest{
+			Scenario: cbctest.Scenario(r.PostFormValue("scenario")),
+			Network:  r.PostFormValue("network"),
+		})
+		if err != nil {
+			location += "?" + url.Values{"error": {err.Error()}}.Encode()
+		}
+		http.Redirect(w, r, location, http.StatusSeeOther)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := hostedPage{
+		Charge:    charge,
+		Status:    coinbasecommerce.ChargeStatusNew,
+		Scenarios: cbctest.Scenarios,
+		Error:     r.URL.Query().Get("error"),
+	}
+	if len(charge.Timeline) != 0 {
+		page.Status = charge.Timeline[len(charge.Timeline)-1].Status
+	}
+	for network, price := range charge.Pricing {
+		if network != "local" {
+			page.Prices = append(page.Prices, hostedPagePrice{Network: network, Price: price})
+		}
+	}
+	sort.Slice(page.Prices, func(i, j int) bool { return page.Prices[i].Network < page.Prices[j].Network })
+	page.RedirectURL, page.CancelURL = h.server.RedirectURLs(charge.Code)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	hostedPageTemplate.Execute(w, &page)
+}
+
+var hostedPageTemplate = template.Must(template.New("hosted").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Charge.Name}} | Coinbase Commerce (mock)</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+.status { font-weight: bold; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Charge.Name}}</h1>
+<p>{{.Charge.Description}}</p>
+{{if eq .Charge.PricingType "fixed_price"}}{{with index .Charge.Pricing "local"}}<p>Price: {{.Amount}} {{.Currency}}</p>{{end}}{{end}}
+<p>Status: <span class="status">{{.Status}}</span> &middot; expires at {{.Charge.ExpiresAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+
+<form method="post">
+{{if .Prices}}<p>Pay with:
+<select name="network">
+{{range .Prices}}<option value="{{.Network}}"{{if eq .Network "ethereum"}} selected{{end}}>{{.Price.Amount}} {{.Price.Currency}} ({{.Network}})</option>
+{{end}}</select></p>{{end}}
+<p>Simulate:
+{{range .Scenarios}}<button type="submit" name="scenario" value="{{.}}">{{.}}</button>
+{{end}}</p>
+</form>
+
+<h2>Timeline</h2>
+<ul>
+{{range .Charge.Timeline}}<li>{{.Time.Format "2006-01-02 15:04:05 MST"}}: {{.Status}}{{with .Context}} ({{.}}){{end}}</li>
+{{end}}</ul>
+
+{{if and .RedirectURL (eq .Status "COMPLETED")}}<p><a href="{{.RedirectURL}}">Return to the merchant</a></p>{{end}}
+{{if and .CancelURL (eq .Status "NEW")}}<p><a href="{{.CancelURL}}">Cancel payment</a></p>{{end}}
+</body>
+</html>
+`))
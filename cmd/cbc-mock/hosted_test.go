@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+func TestHostedPage(t *testing.T) {
+	server := cbctest.New()
+	fixed := server.AddCharge(coinbasecommerce.Charge{
+		Name:        "Coffee",
+		PricingType: coinbasecommerce.PricingTypeFixed,
+		Pricing:     map[string]coinbasecommerce.Money{"local": {Amount: 2.5, Currency: "USD"}},
+	})
+	noPrice := server.AddCharge(coinbasecommerce.Charge{
+		Name:        "Donation",
+		PricingType: coinbasecommerce.PricingTypeNone,
+		Pricing:     map[string]coinbasecommerce.Money{"local": {Amount: 100, Currency: "USD"}},
+	})
+	handler := &hostedPageHandler{server: server}
+
+	tests := []struct {
+		name           string
+		code           string
+		method         string
+		wantStatusCode int
+		wantContains   []string
+		wantNotContain []string
+	}{
+		{
+			name:           "fixed price charge",
+			code:           fixed.Code,
+			method:         http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantContains:   []string{"<h1>Coffee</h1>", "Price: 2.5 USD", "Status: <span class=\"status\">NEW</span>", `value="bitcoin"`},
+		},
+		{
+			name:           "charge without a price",
+			code:           noPrice.Code,
+			method:         http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantContains:   []string{"<h1>Donation</h1>"},
+			wantNotContain: []string{"Price:"},
+		},
+		{name: "unknown charge", code: "MISSING", method: http.MethodGet, wantStatusCode: http.StatusNotFound},
+		{name: "unsupported method", code: fixed.Code, method: http.MethodPut, wantStatusCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, httptest.NewRequest(tt.method, hostedPagePath+tt.code, nil))
+			if response.Code != tt.wantStatusCode {
+				t.Fatalf("status code = %d, want %d", response.Code, tt.wantStatusCode)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(response.Body.String(), want) {
+					t.Errorf("page doesn't contain %s:\n%s", want, response.Body)
+				}
+			}
+			for _, unwanted := range tt.wantNotContain {
+				if strings.Contains(response.Body.String(), unwanted) {
+					t.Errorf("page contains %s:\n%s", unwanted, response.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestHostedPageSimulate(t *testing.T) {
+	server := cbctest.New()
+	code := server.AddCharge(coinbasecommerce.Charge{}).Code
+	handler := &hostedPageHandler{server: server}
+
+	tests := []struct {
+		name       string
+		scenario   cbctest.Scenario
+		wantError  bool
+		wantStatus coinbasecommerce.ChargeStatus
+	}{
+		{"first payment", cbctest.ScenarioCompleted, false, coinbasecommerce.ChargeStatusCompleted},
+		{"second payment", cbctest.ScenarioCompleted, true, coinbasecommerce.ChargeStatusCompleted},
+	}
+	for _, tt := range tests {
+		form := url.Values{"scenario": {string(tt.scenario)}, "network": {"litecoin"}}
+		request := httptest.NewRequest(http.MethodPost, hostedPagePath+code, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		if response.Code != http.StatusSeeOther {
+			t.Fatalf("%s: status code = %d, want %d", tt.name, response.Code, http.StatusSeeOther)
+		}
+		location, err := url.Parse(response.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.Path != hostedPagePath+code {
+			t.Errorf("%s: redirected to %s, want the page of the charge", tt.name, location)
+		}
+		if hasError := location.Query().Get("error") != ""; hasError != tt.wantError {
+			t.Errorf("%s: redirected to %s, want an error %v", tt.name, location, tt.wantError)
+		}
+		charge, _ := server.Charge(code)
+		if status := charge.Timeline[len(charge.Timeline)-1].Status; status != tt.wantStatus {
+			t.Errorf("%s: status = %s, want %s", tt.name, status, tt.wantStatus)
+		}
+		if payment := charge.Payments[0].(cbctest.Payment); payment.Network != "litecoin" {
+			t.Errorf("%s: payment network = %s, want litecoin", tt.name, payment.Network)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Command cbc-mock serves a fake Coinbase Commerce API on a local port, so
+// that the whole payment flow can be clicked through without an account.
+//
+// Usage:
+//
+//	cbc-mock [-addr localhost:4242] [-seed seed.json] [-api-keys key1,key2]
+//	         [-webhook-url http://localhost:3000/webhooks -webhook-secret secret]
+//
+// Besides the API, it serves:
+//
+//	GET  /pay/{code}                          the hosted payment page of a charge
+//	GET  /_admin/charges                      all the charges
+//	POST /_admin/charges/{code}/simulate      simulates a payment scenario, e.g.
+//	                                          {"scenario": "underpaid", "network": "bitcoin"}
+//	GET  /_admin/webhooks                     the webhook deliveries
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+func main() {
+	cfg, err := parseFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		usageError(err.Error())
+	}
+
+	optionFuncs := []cbctest.OptionFunc{
+		cbctest.OptionAPIKeys(cfg.apiKeys...),
+		cbctest.OptionHostedURL(cfg.publicURL + hostedPagePath),
+	}
+	if cfg.webhookURL != "" {
+		optionFuncs = append(optionFuncs, cbctest.OptionWebhook(cfg.webhookURL, cfg.webhookSecret))
+	}
+	server := cbctest.New(optionFuncs...)
+
+	if cfg.seedPath != "" {
+		seed, err := cbctest.LoadSeed(cfg.seedPath)
+		if err != nil {
+			log.Fatalf("failed to load the seed: %v", err)
+		}
+		server.Seed(seed)
+		log.Printf("seeded %d charges and %d checkouts", len(seed.Charges), len(seed.Checkouts))
+	}
+
+	log.Printf("serving the fake Coinbase Commerce API at %s", cfg.publicURL)
+	log.Fatal(http.ListenAndServe(cfg.addr, logRequests(newHandler(server))))
+}
+
+// config contains the values of the flags.
+type config struct {
+	addr          string
+	publicURL     string
+	seedPath      string
+	apiKeys       []string
+	webhookURL    string
+	webhookSecret string
+}
+
+// parseFlags defines the flags on the flag set, parses the arguments and
+// checks the values of the flags.
+func parseFlags(flagSet *flag.FlagSet, args []string) (config, error) {
+	addr := flagSet.String("addr", "localhost:4242", "address to listen on")
+	publicURL := flagSet.String("public-url", "", "URL that the server is reachable at (default http://{addr})")
+	seedPath := flagSet.String("seed", "", "JSON file with the charges and checkouts to start with")
+	apiKeys := flagSet.String("api-keys", cbctest.APIKey, "comma-separated API keys to accept")
+	webhookURL := flagSet.String("webhook-url", "", "URL to send the events of the charges to")
+	webhookSecret := flagSet.String("webhook-secret", "", "shared secret to sign the events with")
+	if err := flagSet.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	if *webhookURL != "" && *webhookSecret == "" {
+		return config{}, errors.New("-webhook-secret is required with -webhook-url, or the webhooks can't be verified")
+	}
+	var keys []string
+	for _, key := range strings.Split(*apiKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return config{}, errors.New("-api-keys must contain at least one API key")
+	}
+
+	if *publicURL == "" {
+		*publicURL = "http://" + *addr
+	}
+	return config{
+		addr:          *addr,
+		publicURL:     strings.TrimRight(*publicURL, "/"),
+		seedPath:      *seedPath,
+		apiKeys:       keys,
+		webhookURL:    *webhookURL,
+		webhookSecret: *webhookSecret,
+	}, nil
+}
+
+// newHandler serves the fake API, its admin endpoints and its hosted pages.
+func newHandler(server *cbctest.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(adminPath, &adminHandler{server: server})
+	mux.Handle(hostedPagePath, &hostedPageHandler{server: server})
+	mux.Handle("/", server)
+	return mux
+}
+
+// usageError prints the message and the usage, and then exits with the status
+// code of the usage errors of the flag package.
+func usageError(message string) {
+	fmt.Fprintf(flag.CommandLine.Output(), "cbc-mock: %s\n", message)
+	flag.Usage()
+	os.Exit(2)
+}
+
+func logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.RequestURI())
+		handler.ServeHTTP(w, r)
+	})
+}
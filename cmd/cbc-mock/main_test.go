@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: config{
+				addr:      "localhost:4242",
+				publicURL: "http://localhost:4242",
+				apiKeys:   []string{cbctest.APIKey},
+			},
+		},
+		{
+			name: "every flag",
+			args: []string{
+				"-addr", ":8080", "-public-url", "https://mock.example.com/", "-seed", "seed.json",
+				"-api-keys", " key-1, ,key-2 ", "-webhook-url", "http://localhost:3000/webhooks", "-webhook-secret", "secret",
+			},
+			want: config{
+				addr:          ":8080",
+				publicURL:     "https://mock.example.com",
+				seedPath:      "seed.json",
+				apiKeys:       []string{"key-1", "key-2"},
+				webhookURL:    "http://localhost:3000/webhooks",
+				webhookSecret: "secret",
+			},
+		},
+		{
+			name:    "webhook URL without a secret",
+			args:    []string{"-webhook-url", "http://localhost:3000/webhooks"},
+			wantErr: true,
+		},
+		{
+			name:    "no API keys",
+			args:    []string{"-api-keys", " , "},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-port", "4242"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("cbc-mock", flag.ContinueOnError)
+			flagSet.SetOutput(ioutil.Discard)
+			got, err := parseFlags(flagSet, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags() error = %v, want an error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFlags() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	server := cbctest.New()
+	charge := server.AddCharge(coinbasecommerce.Charge{Name: "Coffee"})
+	handler := httptest.NewServer(newHandler(server))
+	defer handler.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		apiKey         string
+		wantStatusCode int
+	}{
+		{"API", "/charges", cbctest.APIKey, http.StatusOK},
+		{"API without an API key", "/charges", "", http.StatusUnauthorized},
+		{"admin without an API key", "/_admin/charges", "", http.StatusOK},
+		{"hosted page without an API key", "/pay/" + charge.Code, "", http.StatusOK},
+		{"unknown admin endpoint", "/_admin/checkouts", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, handler.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.apiKey != "" {
+				request.Header.Set(coinbasecommerce.APIHeaderAPIKey, tt.apiKey)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("GET %s status code = %d, want %d", tt.path, response.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Environment variables that are read by LoadConfigFromEnv.
+const (
+	EnvAPIKey         = "COINBASE_COMMERCE_API_KEY"
+	EnvAPIVersion     = "COINBASE_COMMERCE_API_VERSION"
+	EnvBaseURL        = "COINBASE_COMMERCE_BASE_URL"
+	EnvTimeout        = "COINBASE_COMMERCE_TIMEOUT"
+	EnvWebhookSecrets = "COINBASE_COMMERCE_WEBHOOK_SECRETS"
+)
+
+// apiVersionLayout is the layout of the API versions, which are dates.
+const apiVersionLayout = "2006-01-02"
+
+// Config contains the configuration of an integration with the Coinbase
+// Commerce API, as loaded from the environment and/or a file.
+type Config struct {
+	// APIKey is the API key (required).
+	APIKey string
+	// APIVersion is the API version, e.g. "2018-03-22" (required).
+	APIVersion string
+	// BaseURL is the base URL of the API; DefaultAPIBaseURL if empty.
+	BaseURL string
+	// Timeout is the timeout of the HTTP client; no timeout if zero.
+	Timeout time.Duration
+	// WebhookSecrets are the active shared secrets of the webhook
+	// subscriptions; more than one during a secret rotation.
+	WebhookSecrets []string
+}
+
+// Validate checks that the API key isn't empty, that the API version is a
+// well-formed date, and that the base URL, if any, is an absolute URL.
+func (cfg Config) Validate() error {
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		return LocalError{Inner: ErrInvalidAPIKey}
+	}
+	if _, err := time.Parse(apiVersionLayout, cfg.APIVersion); err != nil {
+		return LocalError{Inner: fmt.Errorf("%w: %q", ErrInvalidAPIVersion, cfg.APIVersion)}
+	}
+	if cfg.BaseURL != "" {
+		if u, err := url.Parse(cfg.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return LocalError{Inner: fmt.Errorf("%w: %q", ErrInvalidBaseURL, cfg.BaseURL)}
+		}
+	}
+	if cfg.Timeout < 0 {
+		return LocalError{Inner: fmt.Errorf("%w: %s", ErrInvalidTimeout, cfg.Timeout)}
+	}
+	return nil
+}
+
+// APIConfig validates the configuration and then creates an API
+// configuration from it. If the configuration has a timeout, the API call
+// contexts of the API configuration use the HTTP client of the configuration
+// by default.
+func (cfg Config) APIConfig(optionFuncs ...APIConfigOptionFunc) (*APIConfig, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout != 0 {
+		optionFuncs = append([]APIConfigOptionFunc{APIConfigOptionHTTPClient(cfg.HTTPClient())}, optionFuncs...)
+	}
+	if cfg.BaseURL != "" {
+		optionFuncs = append([]APIConfigOptionFunc{APIConfigOptionBaseURL(cfg.BaseURL)}, optionFuncs...)
+	}
+	return NewAPIConfig(cfg.APIKey, cfg.APIVersion, optionFuncs...), nil
+}
+
+// HTTPClient creates an HTTP client that has the timeout of the
+// configuration.
+func (cfg Config) HTTPClient() *http.Client {
+	return &http.Client{Timeout: cfg.Timeout}
+}
+
+// ValidateAPIConfig checks the API configuration the same way as
+// Config.Validate does, since NewAPIConfig accepts any API key and version.
+// The API key is obtained from the key provider of the configuration.
+func ValidateAPIConfig(apiConfig *APIConfig) error {
+	apiKey, err := apiConfig.KeyProvider().APIKey(context.Background())
+	if err != nil {
+		return LocalError{Inner: err}
+	}
+	return Config{
+		APIKey:     apiKey,
+		APIVersion: apiConfig.Version(),
+		BaseURL:    apiConfig.BaseURL(),
+	}.Validate()
+}
+
+type fileConfig struct {
+	APIKey         string   `json:"api_key"`
+	APIVersion     string   `json:"api_version"`
+	BaseURL        string   `json:"base_url"`
+	Timeout        string   `json:"timeout"`
+	WebhookSecrets []string `json:"webhook_secrets"`
+}
+
+// LoadConfigFromFile reads the configuration from a JSON file, or from a YAML
+// file if the file's extension is ".yaml" or ".yml". The keys of the file are
+// "api_key", "api_version", "base_url", "timeout", e.g. "30s", and
+// "webhook_secrets". Only flat YAML files are supported, i.e. plain, single-
+// and double-quoted scalars, block lists of scalars and single-line flow lists
+// of scalars, e.g. [a, "b,c"]. The configuration isn't validated.
+func LoadConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, LocalError{Inner: err}
+	}
+
+	var file fileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = parseYAMLConfig(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return Config{}, LocalError{Inner: fmt.Errorf("%s: %w", path, err)}
+	}
+
+	cfg := Config{
+		APIKey:         file.APIKey,
+		APIVersion:     file.APIVersion,
+		BaseURL:        file.BaseURL,
+		WebhookSecrets: file.WebhookSecrets,
+	}
+	if file.Timeout != "" {
+		if cfg.Timeout, err = time.ParseDuration(file.Timeout); err != nil {
+			return Config{}, LocalError{Inner: fmt.Errorf("%w: %q", ErrInvalidTimeout, file.Timeout)}
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfigFromEnv reads the configuration from the environment variables,
+// e.g. COINBASE_COMMERCE_API_KEY. COINBASE_COMMERCE_WEBHOOK_SECRETS is a
+// comma-separated list. The configuration isn't validated.
+func LoadConfigFromEnv() (Config, error) {
+	return mergeEnvConfig(Config{})
+}
+
+func mergeEnvConfig(cfg Config) (Config, error) {
+	if apiKey, ok := os.LookupEnv(EnvAPIKey); ok {
+		cfg.APIKey = apiKey
+	}
+	if apiVersion, ok := os.LookupEnv(EnvAPIVersion); ok {
+		cfg.APIVersion = apiVersion
+	}
+	if baseURL, ok := os.LookupEnv(EnvBaseURL); ok {
+		cfg.BaseURL = baseURL
+	}
+	if timeout, ok := os.LookupEnv(EnvTimeout); ok && timeout != "" {
+		var err error
+		if cfg.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return Config{}, LocalError{Inner: fmt.Errorf("%w: %q", ErrInvalidTimeout, timeout)}
+		}
+	}
+	if secrets, ok := os.LookupEnv(EnvWebhookSecrets); ok {
+		cfg.WebhookSecrets = nil
+		for _, secret := range strings.Split(secrets, ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				cfg.WebhookSecrets = append(cfg.WebhookSecrets, secret)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the configuration from the file, if path isn't empty, and
+// then from the environment variables, which take precedence, and then
+// validates it.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		var err error
+		if cfg, err = LoadConfigFromFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	cfg, err := mergeEnvConfig(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// LoadAPIConfig loads the configuration like LoadConfig does, and then
+// creates an API configuration from it.
+func LoadAPIConfig(path string, optionFuncs ...APIConfigOptionFunc) (*APIConfig, Config, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	apiConfig, err := cfg.APIConfig(optionFuncs...)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	return apiConfig, cfg, nil
+}
+
+var errInvalidYAML = errors.New("invalid yaml")
+
+// parseYAMLConfig parses a flat YAML document, i.e. one whose values are
+// either scalars or lists of scalars, into the file configuration.
+func parseYAMLConfig(data []byte, file *fileConfig) error {
+	values := make(map[string]interface{})
+	var listKey string
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return fmt.Errorf("%w: line %d: unexpected list item", errInvalidYAML, i+1)
+			}
+			item, err := unquoteYAML(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return fmt.Errorf("%w: line %d: %s", errInvalidYAML, i+1, err)
+			}
+			list, _ := values[listKey].([]string)
+			values[listKey] = append(list, item)
+			continue
+		}
+
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 || line != trimmed {
+			return fmt.Errorf("%w: line %d: expected a top-level key", errInvalidYAML, i+1)
+		}
+		key, value := strings.TrimSpace(trimmed[:colon]), strings.TrimSpace(trimmed[colon+1:])
+		listKey = ""
+		switch {
+		case value == "":
+			listKey = key
+			values[key] = []string(nil)
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var list []string
+			for _, item := range splitYAMLFlowList(value[1 : len(value)-1]) {
+				if item = strings.TrimSpace(item); item != "" {
+					unquoted, err := unquoteYAML(item)
+					if err != nil {
+						return fmt.Errorf("%w: line %d: %s", errInvalidYAML, i+1, err)
+					}
+					list = append(list, unquoted)
+				}
+			}
+			values[key] = list
+		default:
+			unquoted, err := unquoteYAML(value)
+			if err != nil {
+				return fmt.Errorf("%w: line %d: %s", errInvalidYAML, i+1, err)
+			}
+			values[key] = unquoted
+		}
+	}
+
+	scalar := func(key string) (string, error) {
+		switch value := values[key].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return value, nil
+		default:
+			return "", fmt.Errorf("%w: %s must be a scalar", errInvalidYAML, key)
+		}
+	}
+	var err error
+	if file.APIKey, err = scalar("api_key"); err != nil {
+		return err
+	}
+	if file.APIVersion, err = scalar("api_version"); err != nil {
+		return err
+	}
+	if file.BaseURL, err = scalar("base_url"); err != nil {
+		return err
+	}
+	if file.Timeout, err = scalar("timeout"); err != nil {
+		return err
+	}
+	switch secrets := values["webhook_secrets"].(type) {
+	case nil:
+	case []string:
+		file.WebhookSecrets = secrets
+	case string:
+		file.WebhookSecrets = []string{secrets}
+	}
+	return nil
+}
+
+func stripYAMLComment(line string) string {
+	inQuote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inQuote != 0:
+			if c == '\\' && inQuote == '"' {
+				i++ // the escaped character can't end the quote
+			} else if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitYAMLFlowList splits the items of a flow list, i.e. the contents of
+// its brackets, on the commas that aren't quoted.
+func splitYAMLFlowList(list string) []string {
+	var items []string
+	inQuote := byte(0)
+	start := 0
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case inQuote != 0:
+			if c == '\\' && inQuote == '"' {
+				i++ // the escaped character can't end the quote
+			} else if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == ',':
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, list[start:])
+}
+
+// unquoteYAML returns the value of a scalar. In a double-quoted scalar, the
+// escape sequences of YAML are decoded, e.g. \" and \x41; the unknown ones are
+// rejected rather than loaded verbatim. In a single-quoted scalar, two single
+// quotes are a quote.
+func unquoteYAML(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	switch value[0] {
+	case '"':
+		unquoted, ok := unquoteYAMLDoubleQuoted(value)
+		if !ok {
+			return "", fmt.Errorf("invalid double-quoted scalar %s", value)
+		}
+		return unquoted, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("unterminated single-quoted scalar %s", value)
+		}
+		inner := value[1 : len(value)-1]
+		if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+			return "", fmt.Errorf("invalid single-quoted scalar %s", value)
+		}
+		return strings.ReplaceAll(inner, "''", "'"), nil
+	}
+	return value, nil
+}
+
+// yamlEscapes are the characters of the single-character escape sequences of
+// YAML, e.g. \t.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// yamlUnicodeEscapes are the lengths of the hexadecimal codes of the
+// escape sequences of YAML that start with the characters, e.g. \u00e9.
+var yamlUnicodeEscapes = map[byte]int{'x': 2, 'u': 4, 'U': 8}
+
+// unquoteYAMLDoubleQuoted decodes a double-quoted scalar, which must be
+// surrounded by the quotes.
+func unquoteYAMLDoubleQuoted(value string) (string, bool) {
+	if len(value) < 2 || value[len(value)-1] != '"' {
+		return "", false
+	}
+	inner := value[1 : len(value)-1]
+
+	var unquoted strings.Builder
+	for i := 0; i < len(inner); i++ {
+		switch c := inner[i]; {
+		case c == '"':
+			return "", false
+		case c != '\\':
+			unquoted.WriteByte(c)
+		case i+1 == len(inner):
+			return "", false
+		default:
+			i++
+			if escaped, ok := yamlEscapes[inner[i]]; ok {
+				unquoted.WriteString(escaped)
+				continue
+			}
+			length, ok := yamlUnicodeEscapes[inner[i]]
+			if !ok || i+length >= len(inner) {
+				return "", false
+			}
+			code, err := strconv.ParseUint(inner[i+1:i+1+length], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", false
+			}
+			unquoted.WriteRune(rune(code))
+			i += length
+		}
+	}
+	return unquoted.String(), true
+}
@@ -0,0 +1,279 @@
+package coinbasecommerce_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+func TestLoadConfigFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    coinbasecommerce.Config
+		wantErr bool
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			content: `{"api_key": "key", "api_version": "2018-03-22", "base_url": "https://example.com",
+				"timeout": "30s", "webhook_secrets": ["a", "b"]}`,
+			want: coinbasecommerce.Config{
+				APIKey:         "key",
+				APIVersion:     "2018-03-22",
+				BaseURL:        "https://example.com",
+				Timeout:        30 * time.Second,
+				WebhookSecrets: []string{"a", "b"},
+			},
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: "---\n# the API\napi_key: key # inline comment\napi_version: '2018-03-22'\n" +
+				"timeout: 1m\nwebhook_secrets:\n  - a\n  - \"b\"\n",
+			want: coinbasecommerce.Config{
+				APIKey:         "key",
+				APIVersion:     "2018-03-22",
+				Timeout:        time.Minute,
+				WebhookSecrets: []string{"a", "b"},
+			},
+		},
+		{
+			name:    "yaml flow list",
+			file:    "config.yml",
+			content: "webhook_secrets: [a, 'b', \"c\"]\n",
+			want:    coinbasecommerce.Config{WebhookSecrets: []string{"a", "b", "c"}},
+		},
+		{
+			name:    "yaml single secret",
+			file:    "config.yml",
+			content: "webhook_secrets: a\n",
+			want:    coinbasecommerce.Config{WebhookSecrets: []string{"a"}},
+		},
+		{
+			name:    "yaml double-quoted escapes",
+			file:    "config.yaml",
+			content: `api_key: "a\"b\\c\n" # comment` + "\n",
+			want:    coinbasecommerce.Config{APIKey: "a\"b\\c\n"},
+		},
+		{
+			name:    "yaml single-quoted quote",
+			file:    "config.yaml",
+			content: "api_key: 'it''s # not a comment'\n",
+			want:    coinbasecommerce.Config{APIKey: "it's # not a comment"},
+		},
+		{
+			name:    "yaml hash inside a value",
+			file:    "config.yaml",
+			content: "api_key: a#b\n",
+			want:    coinbasecommerce.Config{APIKey: "a#b"},
+		},
+		{
+			name:    "yaml flow list with quoted commas",
+			file:    "config.yaml",
+			content: `webhook_secrets: [a, "b,c", 'd,''e', "f\",g"]` + "\n",
+			want:    coinbasecommerce.Config{WebhookSecrets: []string{"a", "b,c", "d,'e", `f",g`}},
+		},
+		{
+			name:    "yaml escapes",
+			file:    "config.yaml",
+			content: `api_key: "\e\x41\u00e9\U0001F600\/\N\_\ \0"` + "\n",
+			want:    coinbasecommerce.Config{APIKey: "\x1bA\u00e9\U0001F600/\u0085\u00a0 \x00"},
+		},
+		{
+			name:    "yaml unsupported escape",
+			file:    "config.yaml",
+			content: `api_key: "a\qb"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml octal escape of go",
+			file:    "config.yaml",
+			content: `api_key: "\101"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml short unicode escape",
+			file:    "config.yaml",
+			content: `api_key: "\u00e"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml unescaped double quote",
+			file:    "config.yaml",
+			content: `api_key: "a"b"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml unterminated quote",
+			file:    "config.yaml",
+			content: "api_key: \"abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml nested key",
+			file:    "config.yaml",
+			content: "api:\n  key: abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml list item without a key",
+			file:    "config.yaml",
+			content: "- abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml list as a scalar",
+			file:    "config.yaml",
+			content: "api_key:\n  - a\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			file:    "config.json",
+			content: `{"timeout": "soon"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			file:    "config.json",
+			content: `{"api_key": `,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, cleanup := writeTempFile(t, test.file, test.content)
+			defer cleanup()
+
+			cfg, err := coinbasecommerce.LoadConfigFromFile(path)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("LoadConfigFromFile() = %+v, want an error", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfigFromFile() = %v", err)
+			}
+			if !reflect.DeepEqual(cfg, test.want) {
+				t.Errorf("LoadConfigFromFile() = %+v, want %+v", cfg, test.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := coinbasecommerce.Config{APIKey: "key", APIVersion: "2018-03-22"}
+	tests := []struct {
+		name    string
+		modify  func(cfg *coinbasecommerce.Config)
+		wantErr error
+	}{
+		{"valid", func(cfg *coinbasecommerce.Config) {}, nil},
+		{"valid base url", func(cfg *coinbasecommerce.Config) { cfg.BaseURL = "http://localhost:4242" }, nil},
+		{"blank api key", func(cfg *coinbasecommerce.Config) { cfg.APIKey = " " }, coinbasecommerce.ErrInvalidAPIKey},
+		{"bad version", func(cfg *coinbasecommerce.Config) { cfg.APIVersion = "v1" }, coinbasecommerce.ErrInvalidAPIVersion},
+		{"relative base url", func(cfg *coinbasecommerce.Config) { cfg.BaseURL = "/api" }, coinbasecommerce.ErrInvalidBaseURL},
+		{"negative timeout", func(cfg *coinbasecommerce.Config) { cfg.Timeout = -time.Second }, coinbasecommerce.ErrInvalidTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := valid
+			test.modify(&cfg)
+			err := cfg.Validate()
+			if test.wantErr == nil && err != nil || test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("Validate() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path, cleanup := writeTempFile(t, "config.yaml", "api_key: file-key\napi_version: 2018-03-22\ntimeout: 5s\n")
+	defer cleanup()
+	defer setEnv(coinbasecommerce.EnvAPIKey, "env-key")()
+	defer setEnv(coinbasecommerce.EnvWebhookSecrets, " a, ,b ")()
+
+	cfg, err := coinbasecommerce.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := coinbasecommerce.Config{
+		APIKey:         "env-key",
+		APIVersion:     "2018-03-22",
+		Timeout:        5 * time.Second,
+		WebhookSecrets: []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadAPIConfigTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		optionFuncs []coinbasecommerce.APIConfigOptionFunc
+		wantTimeout time.Duration
+	}{
+		{"timeout", "timeout: 5s\n", nil, 5 * time.Second},
+		{"no timeout", "", nil, 0},
+		{"http client option", "timeout: 5s\n", []coinbasecommerce.APIConfigOptionFunc{
+			coinbasecommerce.APIConfigOptionHTTPClient(&http.Client{Timeout: time.Second}),
+		}, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, cleanup := writeTempFile(t, "config.yaml", "api_key: key\napi_version: 2018-03-22\n"+test.content)
+			defer cleanup()
+
+			apiConfig, _, err := coinbasecommerce.LoadAPIConfig(path, test.optionFuncs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			apiCallContext := coinbasecommerce.NewAPICallContext(apiConfig)
+			if got := apiCallContext.HTTPClient().Timeout; got != test.wantTimeout {
+				t.Errorf("HTTPClient().Timeout = %s, want %s", got, test.wantTimeout)
+			}
+		})
+	}
+}
+
+// writeTempFile writes the content to a file with the name in a new
+// temporary directory, which is removed by the returned function.
+func writeTempFile(t *testing.T, name, content string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "coinbasecommerce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// setEnv sets the environment variable, which is restored by the returned
+// function.
+func setEnv(key, value string) func() {
+	previous, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
@@ -1,28 +1,118 @@
 package coinbasecommerce
 
 import (
+	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
+// APIError type constants. These are the types of the errors that are
+// documented by the Coinbase Commerce API, plus the types that are given to
+// error responses that don't contain an error object.
+const (
+	APIErrorTypeNotFound            = "not_found"
+	APIErrorTypeParamRequired       = "param_required"
+	APIErrorTypeValidation          = "validation_error"
+	APIErrorTypeInvalidRequest      = "invalid_request"
+	APIErrorTypeAuthentication      = "authentication_error"
+	APIErrorTypeAuthorization       = "authorization_error"
+	APIErrorTypeRateLimitExceeded   = "rate_limit_exceeded"
+	APIErrorTypeInternalServerError = "internal_server_error"
+	APIErrorTypeServiceUnavailable  = "service_unavailable"
+	APIErrorTypeHTTPError           = "http_error"
+)
+
+// APIFieldError contains the details of a problem with a field of a request.
+type APIFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // APIError contains the details of the error that was received
 // from the Coinbase Commerce API.
 type APIError struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	// Errors contains the field-level details of the error, if the API
+	// provided them.
+	Errors []APIFieldError `json:"errors,omitempty"`
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"-"`
+	// Header contains the headers of the response.
+	Header http.Header `json:"-"`
+	// RequestID is the ID that the API gave to the request, if there's any.
+	RequestID string `json:"-"`
+	// RawBody is the body of the response; truncated if it's too long.
+	RawBody string `json:"-"`
 }
 
 func (e APIError) Error() string {
-	return fmt.Sprintf("coinbase commerce api error: %s (%s)", e.Type, e.Message)
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("coinbase commerce api error: %s (%s)", e.Type, e.Message)
+	}
+	return fmt.Sprintf(
+		"coinbase commerce api error: %s (%s), status code %d",
+		e.Type, e.Message, e.StatusCode,
+	)
 }
 
-// Is checks if e and target are of the same type
+// Is checks if e and target are of the same type. target may either be an
+// APIError or a pointer to one.
 func (e APIError) Is(target error) bool {
-	if target == nil {
+	switch t := target.(type) {
+	case APIError:
+		return t.Type == e.Type
+	case *APIError:
+		return t != nil && t.Type == e.Type
+	default:
 		return false
 	}
-	t, ok := target.(*APIError)
-	return ok && t.Type == e.Type
+}
+
+// As sets target to e if target is a pointer to either an APIError or a
+// pointer to one, so that errors.As works with both forms of the error.
+func (e APIError) As(target interface{}) bool {
+	switch t := target.(type) {
+	case *APIError:
+		*t = e
+		return true
+	case **APIError:
+		*t = &e
+		return true
+	default:
+		return false
+	}
+}
+
+// FieldErrors returns the field-level details of a validation error. If the
+// API didn't provide them, they're derived from the message of the error when
+// possible, e.g. "Required parameter missing: name".
+func (e APIError) FieldErrors() []APIFieldError {
+	if len(e.Errors) != 0 || e.Type != APIErrorTypeParamRequired {
+		return e.Errors
+	}
+
+	i := strings.LastIndex(e.Message, ":")
+	if i < 0 {
+		return nil
+	}
+	var fieldErrors []APIFieldError
+	for _, field := range strings.Split(e.Message[i+1:], ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fieldErrors = append(fieldErrors, APIFieldError{
+				Field:   field,
+				Message: "required parameter missing",
+			})
+		}
+	}
+	return fieldErrors
 }
 
 // ReturnAPIErrorAsError does what it says it does. We need to explicitly check if
@@ -37,9 +127,89 @@ func ReturnAPIErrorAsError(apiError *APIError) error {
 
 // API Errors
 var (
-	ErrAPIInvalidRequest error = &APIError{Type: "invalid_request"}
+	ErrAPINotFound            error = &APIError{Type: APIErrorTypeNotFound}
+	ErrAPIParamRequired       error = &APIError{Type: APIErrorTypeParamRequired}
+	ErrAPIValidation          error = &APIError{Type: APIErrorTypeValidation}
+	ErrAPIInvalidRequest      error = &APIError{Type: APIErrorTypeInvalidRequest}
+	ErrAPIAuthentication      error = &APIError{Type: APIErrorTypeAuthentication}
+	ErrAPIAuthorization       error = &APIError{Type: APIErrorTypeAuthorization}
+	ErrAPIRateLimitExceeded   error = &APIError{Type: APIErrorTypeRateLimitExceeded}
+	ErrAPIInternalServerError error = &APIError{Type: APIErrorTypeInternalServerError}
+	ErrAPIServiceUnavailable  error = &APIError{Type: APIErrorTypeServiceUnavailable}
+	ErrAPIHTTPError           error = &APIError{Type: APIErrorTypeHTTPError}
 )
 
+// IsNotFound returns true if err is caused by a resource that doesn't exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrAPINotFound)
+}
+
+// IsAuth returns true if err is caused by a missing, invalid or insufficient
+// API key.
+func IsAuth(err error) bool {
+	return errors.Is(err, ErrAPIAuthentication) || errors.Is(err, ErrAPIAuthorization)
+}
+
+// IsValidation returns true if err is caused by an invalid request. See
+// APIError.FieldErrors for the details.
+func IsValidation(err error) bool {
+	return errors.Is(err, ErrAPIValidation) || errors.Is(err, ErrAPIParamRequired) ||
+		errors.Is(err, ErrAPIInvalidRequest)
+}
+
+// IsRetryable returns true if err is transient, i.e. the call that returned
+// it may succeed if it's made again later. The calls whose contexts were done
+// before they finished return a ContextError, which isn't transient since the
+// caller gave up on them; a timeout of the HTTP client is, even though it's
+// reported as a deadline that was exceeded too.
+func IsRetryable(err error) bool {
+	var apiError APIError
+	if errors.As(err, &apiError) {
+		switch apiError.Type {
+		case APIErrorTypeRateLimitExceeded,
+			APIErrorTypeInternalServerError,
+			APIErrorTypeServiceUnavailable:
+			return true
+		}
+		return apiError.StatusCode == http.StatusTooManyRequests ||
+			apiError.StatusCode >= 500
+	}
+
+	// the caller gave up on the call, so making it again is pointless
+	var contextError ContextError
+	if errors.As(err, &contextError) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return isTransientNetworkError(err)
+}
+
+// isTransientNetworkError returns true if err is a failure of the network
+// that may not happen again, e.g. a connection that was refused or reset, or
+// a timeout. The failures that happen every time, e.g. an untrusted
+// certificate or a URL with an unsupported scheme, are not.
+func isTransientNetworkError(err error) bool {
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthorityError) || errors.As(err, &hostnameError) ||
+		errors.As(err, &certificateInvalidError) {
+		return false
+	}
+
+	// a *url.Error is a net.Error whatever it wraps, so it's the error that it
+	// wraps that tells what failed
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		err = urlError.Err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// the connection was closed before the response was received
+		return true
+	}
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
 // LocalError is an error that occured here in the package
 type LocalError struct {
 	Inner error
@@ -53,17 +223,76 @@ func (e LocalError) Unwrap() error {
 	return e.Inner
 }
 
-// Is checks if e and target are of the same type
+// Is checks if e and target are of the same type. target may either be a
+// LocalError or a pointer to one.
 func (e LocalError) Is(target error) bool {
-	if target == nil {
+	switch target.(type) {
+	case LocalError, *LocalError:
+		return true
+	default:
+		return false
+	}
+}
+
+// ContextError is returned when the context of an API call is done before the
+// call has finished, i.e. the caller gave up on the call. Inner is the error
+// that the call failed with.
+type ContextError struct {
+	Inner error
+}
+
+func (e ContextError) Error() string {
+	return fmt.Sprintf("context done: %v", e.Inner)
+}
+
+func (e ContextError) Unwrap() error {
+	return e.Inner
+}
+
+// Is checks if e and target are of the same type. target may either be a
+// ContextError or a pointer to one.
+func (e ContextError) Is(target error) bool {
+	switch target.(type) {
+	case ContextError, *ContextError:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryError is returned when a request to the Coinbase Commerce API has been
+// attempted more than once. Inner is the error of the last attempt.
+type RetryError struct {
+	Attempts int
+	Inner    error
+}
+
+func (e RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", e.Attempts, e.Inner)
+}
+
+func (e RetryError) Unwrap() error {
+	return e.Inner
+}
+
+// Is checks if e and target are of the same type. target may either be a
+// RetryError or a pointer to one.
+func (e RetryError) Is(target error) bool {
+	switch target.(type) {
+	case RetryError, *RetryError:
+		return true
+	default:
 		return false
 	}
-	_, ok := target.(*LocalError)
-	return ok
 }
 
 // Local inner errors
 var (
 	ErrInvalidChargeIDOrCode = errors.New("invalid charge id or code")
 	ErrInvalidCheckoutID     = errors.New("invalid checkout id")
+	ErrCircuitOpen           = errors.New("circuit breaker is open")
+	ErrInvalidAPIKey         = errors.New("invalid api key")
+	ErrInvalidAPIVersion     = errors.New("invalid api version")
+	ErrInvalidBaseURL        = errors.New("invalid api base url")
+	ErrInvalidTimeout        = errors.New("invalid timeout")
 )
@@ -0,0 +1,103 @@
+package coinbasecommerce_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit exceeded", coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeRateLimitExceeded}, true},
+		{"service unavailable", coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeServiceUnavailable}, true},
+		{"server error status", coinbasecommerce.APIError{StatusCode: http.StatusBadGateway}, true},
+		{"too many requests status", coinbasecommerce.APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"not found", coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeNotFound, StatusCode: 404}, false},
+		{"network error", coinbasecommerce.LocalError{Inner: &net.OpError{Op: "dial", Err: errors.New("refused")}}, true},
+		{"connection closed", coinbasecommerce.LocalError{Inner: &url.Error{Op: "Get", Err: io.EOF}}, true},
+		{"unsupported scheme", coinbasecommerce.LocalError{Inner: &url.Error{Op: "Get", Err: errors.New("unsupported protocol scheme")}}, false},
+		{"untrusted certificate", coinbasecommerce.LocalError{Inner: &url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}}, false},
+		{"canceled", coinbasecommerce.LocalError{Inner: context.Canceled}, false},
+		{"deadline exceeded", fmt.Errorf("get: %w", context.DeadlineExceeded), true},
+		{"context done", coinbasecommerce.LocalError{Inner: coinbasecommerce.ContextError{Inner: context.DeadlineExceeded}}, false},
+		{"retried until the context was done", coinbasecommerce.RetryError{Attempts: 2, Inner: coinbasecommerce.LocalError{
+			Inner: coinbasecommerce.ContextError{Inner: &net.OpError{Op: "dial", Err: errors.New("refused")}},
+		}}, false},
+		{"other local error", coinbasecommerce.LocalError{Inner: errors.New("boom")}, false},
+		{"nil", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := coinbasecommerce.IsRetryable(test.err); got != test.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		clientTimeout time.Duration
+		ctxTimeout    time.Duration
+		want          bool
+	}{
+		{"timeout of the HTTP client", 50 * time.Millisecond, 0, true},
+		{"deadline of the context of the call", 0, 50 * time.Millisecond, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.ctxTimeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.ctxTimeout)
+				defer cancel()
+			}
+			httpClient := &http.Client{Timeout: test.clientTimeout}
+
+			_, _, err := charges.Get(coinbasecommerce.NewAPICallContext(
+				coinbasecommerce.NewAPIConfig("key", "2018-03-22",
+					coinbasecommerce.APIConfigOptionBaseURL(server.URL)),
+				coinbasecommerce.APICallContextOptionHTTPClient(httpClient),
+				coinbasecommerce.APICallContextOptionContext(ctx),
+			), "ABC")
+			if err == nil {
+				t.Fatal("charges.Get() succeeded, want a timeout")
+			}
+			if got := coinbasecommerce.IsRetryable(err); got != test.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, got, test.want)
+			}
+
+			if test.clientTimeout != 0 {
+				// the same error, as it's returned by the HTTP client itself
+				_, err := httpClient.Get(server.URL)
+				if got := coinbasecommerce.IsRetryable(err); err == nil || got != test.want {
+					t.Errorf("IsRetryable(%v) = %v, want %v", err, got, test.want)
+				}
+			}
+		})
+	}
+}
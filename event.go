@@ -0,0 +1,96 @@
+package coinbasecommerce
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// EventType represents the type of an event, i.e. what happened to its
+// resource.
+type EventType string
+
+// EventType constants. These are the types of the events that are sent to
+// the webhook subscriptions.
+const (
+	EventTypeChargeCreated   EventType = "charge:created"
+	EventTypeChargeConfirmed EventType = "charge:confirmed"
+	EventTypeChargeFailed    EventType = "charge:failed"
+	EventTypeChargeDelayed   EventType = "charge:delayed"
+	EventTypeChargePending   EventType = "charge:pending"
+	EventTypeChargeResolved  EventType = "charge:resolved"
+
+	EventTypeInvoiceCreated        EventType = "invoice:created"
+	EventTypeInvoiceViewed         EventType = "invoice:viewed"
+	EventTypeInvoicePaymentPending EventType = "invoice:payment_pending"
+	EventTypeInvoicePaid           EventType = "invoice:paid"
+	EventTypeInvoiceUnresolved     EventType = "invoice:unresolved"
+	EventTypeInvoiceResolved       EventType = "invoice:resolved"
+	EventTypeInvoiceVoided         EventType = "invoice:voided"
+)
+
+// Resource returns the type of the resource of the events of the type, e.g.
+// "charge" for "charge:confirmed".
+func (eventType EventType) Resource() string {
+	if i := strings.Index(string(eventType), ":"); i >= 0 {
+		return string(eventType[:i])
+	}
+	return ""
+}
+
+// Event represents something that happened to a resource, e.g. a charge
+// that was confirmed.
+type Event struct {
+	ID         string    `json:"id"`
+	Resource   string    `json:"resource"`
+	Type       EventType `json:"type"`
+	APIVersion string    `json:"api_version"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Data is the resource as it was after the event happened. It's a Charge
+	// for the charge events, an Invoice for the invoice events, and the raw
+	// JSON, i.e. a json.RawMessage, for the events of the other types.
+	Data interface{} `json:"data"`
+}
+
+// UnmarshalJSON decodes the event, and its data according to its type.
+func (event *Event) UnmarshalJSON(data []byte) error {
+	type plainEvent Event
+	var raw struct {
+		plainEvent
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*event = Event(raw.plainEvent)
+	switch raw.Type.Resource() {
+	case "charge":
+		var charge Charge
+		if err := json.Unmarshal(raw.Data, &charge); err != nil {
+			return err
+		}
+		event.Data = charge
+	case "invoice":
+		var invoice Invoice
+		if err := json.Unmarshal(raw.Data, &invoice); err != nil {
+			return err
+		}
+		event.Data = invoice
+	default:
+		event.Data = raw.Data
+	}
+	return nil
+}
+
+// Charge returns the data of the event if it's a charge.
+func (event Event) Charge() (Charge, bool) {
+	charge, ok := event.Data.(Charge)
+	return charge, ok
+}
+
+// Invoice returns the data of the event if it's an invoice.
+func (event Event) Invoice() (Invoice, bool) {
+	invoice, ok := event.Data.(Invoice)
+	return invoice, ok
+}
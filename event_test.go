@@ -0,0 +1,139 @@
+package coinbasecommerce_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+func TestEventUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		wantType    coinbasecommerce.EventType
+		wantData    interface{}
+		wantCharge  bool
+		wantInvoice bool
+	}{
+		{
+			name:     "charge event",
+			json:     `{"id":"e1","resource":"event","type":"charge:confirmed","data":{"id":"c1","code":"ABC","pricing_type":"fixed_price"}}`,
+			wantType: coinbasecommerce.EventTypeChargeConfirmed,
+			wantData: coinbasecommerce.Charge{
+				ID:          "c1",
+				Code:        "ABC",
+				PricingType: coinbasecommerce.PricingTypeFixed,
+			},
+			wantCharge: true,
+		},
+		{
+			name:     "invoice event",
+			json:     `{"id":"e2","resource":"event","type":"invoice:paid","data":{"id":"i1","code":"DEF","status":"PAID","local_price":{"amount":"2.50","currency":"USD"}}}`,
+			wantType: coinbasecommerce.EventTypeInvoicePaid,
+			wantData: coinbasecommerce.Invoice{
+				ID:         "i1",
+				Code:       "DEF",
+				Status:     coinbasecommerce.InvoiceStatusPaid,
+				LocalPrice: coinbasecommerce.Money{Amount: 2.5, Currency: "USD"},
+			},
+			wantInvoice: true,
+		},
+		{
+			name:     "unknown event type",
+			json:     `{"id":"e3","resource":"event","type":"checkout:created","data":{"id":"k1"}}`,
+			wantType: coinbasecommerce.EventType("checkout:created"),
+			wantData: json.RawMessage(`{"id":"k1"}`),
+		},
+		{
+			name:     "event type without a resource",
+			json:     `{"id":"e4","resource":"event","type":"ping","data":[1,2]}`,
+			wantType: coinbasecommerce.EventType("ping"),
+			wantData: json.RawMessage(`[1,2]`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event coinbasecommerce.Event
+			if err := json.Unmarshal([]byte(tt.json), &event); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if event.Type != tt.wantType || event.Resource != "event" {
+				t.Errorf("Type, Resource = %s, %s, want %s, event", event.Type, event.Resource, tt.wantType)
+			}
+			if !reflect.DeepEqual(event.Data, tt.wantData) {
+				t.Errorf("Data = %#v, want %#v", event.Data, tt.wantData)
+			}
+			if _, ok := event.Charge(); ok != tt.wantCharge {
+				t.Errorf("Charge() ok = %v, want %v", ok, tt.wantCharge)
+			}
+			if _, ok := event.Invoice(); ok != tt.wantInvoice {
+				t.Errorf("Invoice() ok = %v, want %v", ok, tt.wantInvoice)
+			}
+		})
+	}
+}
+
+func TestEventUnmarshalJSONInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"charge that isn't an object", `{"id":"e1","type":"charge:created","data":"c1"}`},
+		{"invoice that isn't an object", `{"id":"e2","type":"invoice:created","data":[]}`},
+		{"malformed event", `{"id":"e3","type":"charge:created","data":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event coinbasecommerce.Event
+			if err := json.Unmarshal([]byte(tt.json), &event); err == nil {
+				t.Errorf("Unmarshal() error = nil, event = %+v, want an error", event)
+			}
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	event := coinbasecommerce.Event{
+		ID:         "e1",
+		Resource:   "event",
+		Type:       coinbasecommerce.EventTypeChargeResolved,
+		APIVersion: "2018-03-22",
+		CreatedAt:  createdAt,
+		Data:       coinbasecommerce.Charge{ID: "c1", Code: "ABC", CreatedAt: createdAt},
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded coinbasecommerce.Event
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	charge, ok := decoded.Charge()
+	if !ok || charge.ID != "c1" || charge.Code != "ABC" || !charge.CreatedAt.Equal(createdAt) {
+		t.Errorf("Charge() = %+v, %v, want the charge c1", charge, ok)
+	}
+	if decoded.ID != event.ID || decoded.APIVersion != event.APIVersion || !decoded.CreatedAt.Equal(createdAt) {
+		t.Errorf("decoded event = %+v, want %+v", decoded, event)
+	}
+}
+
+func TestEventTypeResource(t *testing.T) {
+	tests := []struct {
+		eventType coinbasecommerce.EventType
+		want      string
+	}{
+		{coinbasecommerce.EventTypeChargeCreated, "charge"},
+		{coinbasecommerce.EventTypeInvoicePaymentPending, "invoice"},
+		{"ping", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.eventType.Resource(); got != tt.want {
+			t.Errorf("%s.Resource() = %q, want %q", tt.eventType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"sync"
+)
+
+// IdempotencyStore records the ID of the resource that was created for an
+// idempotency key, so that the resource isn't created again when its creation
+// is retried with the same key.
+type IdempotencyStore interface {
+	// Load returns the ID of the resource that was recorded for the key.
+	Load(ctx context.Context, key string) (id string, ok bool, err error)
+	// Store records the ID of the resource that was created for the key.
+	Store(ctx context.Context, key, id string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps the records in
+// memory. It's safe for concurrent use by multiple goroutines.
+type MemoryIdempotencyStore struct {
+	mu  sync.RWMutex
+	ids map[string]string
+}
+
+// NewMemoryIdempotencyStore creates a new in-memory idempotency store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ids: make(map[string]string)}
+}
+
+// Load returns the ID of the resource that was recorded for the key.
+func (store *MemoryIdempotencyStore) Load(ctx context.Context, key string) (string, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	id, ok := store.ids[key]
+	return id, ok, nil
+}
+
+// Store records the ID of the resource that was created for the key.
+func (store *MemoryIdempotencyStore) Store(ctx context.Context, key, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.ids[key] = id
+	return nil
+}
@@ -0,0 +1,62 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"net/http"
+)
+
+// APICall contains the details of a call to the Coinbase Commerce API. It's
+// passed through the interceptors of the API call context.
+type APICall struct {
+	// Operation is the name of the operation, e.g. "charges.Create".
+	Operation string
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request.
+	URL string
+	// Header contains the additional headers of the request.
+	Header http.Header
+	// Body is the body of the request; equal to nil if there's none.
+	Body []byte
+
+	// Result points to the value that the `data` field of the response is
+	// decoded into, e.g. a *Charge or a *[]Checkout; equal to nil if the
+	// operation has no result.
+	Result interface{}
+	// Pagination points to the value that the `pagination` field of the
+	// response is decoded into; equal to nil if the operation isn't a list.
+	Pagination *Pagination
+	// Warnings contains the warnings that was received from the API.
+	Warnings Warnings
+
+	// StatusCode is the HTTP status code of the response; equal to 0 if no
+	// response was received.
+	StatusCode int
+	// ResponseHeader contains the headers of the response.
+	ResponseHeader http.Header
+	// ResponseBody is the body of the response.
+	ResponseBody []byte
+	// Attempts is the number of times the request was sent.
+	Attempts int
+}
+
+// APIInvoker represents a function that executes an API call.
+type APIInvoker func(ctx context.Context, call *APICall) error
+
+// APIInterceptor represents a function that intercepts an API call. It may
+// modify the request of the call before invoking next, inspect the result and
+// the error of the call after, or short-circuit the call by not invoking next,
+// in which case it's responsible for filling in the result of the call.
+type APIInterceptor func(ctx context.Context, call *APICall, next APIInvoker) error
+
+// ChainAPIInterceptors creates an invoker that passes the call through the
+// interceptors, in order, before invoking the invoker.
+func ChainAPIInterceptors(invoker APIInvoker, interceptors ...APIInterceptor) APIInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, call *APICall) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return invoker
+}
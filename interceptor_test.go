@@ -0,0 +1,164 @@
+package coinbasecommerce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+func TestChainAPIInterceptors(t *testing.T) {
+	errShortCircuited := errors.New("short-circuited")
+	var trace []string
+	tracing := func(name string) coinbasecommerce.APIInterceptor {
+		return func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+			trace = append(trace, name+" before")
+			err := next(ctx, call)
+			trace = append(trace, name+" after")
+			return err
+		}
+	}
+	shortCircuiting := func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+		trace = append(trace, "short-circuit")
+		return errShortCircuited
+	}
+	invoker := func(ctx context.Context, call *coinbasecommerce.APICall) error {
+		trace = append(trace, "invoker")
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		interceptors []coinbasecommerce.APIInterceptor
+		wantTrace    string
+		wantErr      error
+	}{
+		{"no interceptors", nil, "invoker", nil},
+		{
+			name:         "in order",
+			interceptors: []coinbasecommerce.APIInterceptor{tracing("a"), tracing("b")},
+			wantTrace:    "a before,b before,invoker,b after,a after",
+		},
+		{
+			name:         "short-circuited",
+			interceptors: []coinbasecommerce.APIInterceptor{tracing("a"), shortCircuiting, tracing("b")},
+			wantTrace:    "a before,short-circuit,a after",
+			wantErr:      errShortCircuited,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace = nil
+			err := coinbasecommerce.ChainAPIInterceptors(invoker, tt.interceptors...)(
+				context.Background(), &coinbasecommerce.APICall{})
+			if err != tt.wantErr {
+				t.Errorf("invoker() = %v, want %v", err, tt.wantErr)
+			}
+			if got := strings.Join(trace, ","); got != tt.wantTrace {
+				t.Errorf("trace = %s, want %s", got, tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestAPICallThroughInterceptors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"code": "` + r.Header.Get("X-Audit-Id") + `"}}`))
+	}))
+	defer server.Close()
+
+	var seen coinbasecommerce.APICall
+	var seenCode string
+	auditing := func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+		call.Header.Set("X-Audit-Id", "AUDITED")
+		err := next(ctx, call)
+		seen = *call
+		if charge, ok := call.Result.(*coinbasecommerce.Charge); ok {
+			seenCode = charge.Code
+		}
+		return err
+	}
+	apiCallContext := coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig("key", "2018-03-22", coinbasecommerce.APIConfigOptionBaseURL(server.URL)),
+		coinbasecommerce.APICallContextOptionInterceptors(auditing),
+	)
+
+	charge, _, err := charges.Get(apiCallContext, "ABC")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if charge.Code != "AUDITED" {
+		t.Errorf("Get() code = %q, want the header that the interceptor set", charge.Code)
+	}
+	if seen.Operation != "charges.Get" || seen.Method != http.MethodGet || seen.URL != server.URL+"/charges/ABC" {
+		t.Errorf("interceptor saw %s %s %s, want charges.Get GET %s/charges/ABC",
+			seen.Operation, seen.Method, seen.URL, server.URL)
+	}
+	if seen.StatusCode != http.StatusOK || seen.Attempts != 1 || seenCode != "AUDITED" {
+		t.Errorf("interceptor saw status code %d, %d attempts and code %q, want 200, 1 and the decoded result",
+			seen.StatusCode, seen.Attempts, seenCode)
+	}
+
+	// an interceptor that short-circuits the call fills in its result
+	cached := func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+		call.Result.(*coinbasecommerce.Charge).Code = "CACHED"
+		return nil
+	}
+	charge, _, err = charges.Get(apiCallContext.With(coinbasecommerce.APICallContextOptionInterceptors(cached)), "ABC")
+	if err != nil {
+		t.Fatalf("Get() with a short-circuiting interceptor error = %v", err)
+	}
+	if charge.Code != "CACHED" {
+		t.Errorf("Get() code = %q, want the result of the short-circuiting interceptor", charge.Code)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestAPICallContextOptionInterceptorsAppends(t *testing.T) {
+	var trace []string
+	tracing := func(name string) coinbasecommerce.APIInterceptor {
+		return func(ctx context.Context, call *coinbasecommerce.APICall, next coinbasecommerce.APIInvoker) error {
+			trace = append(trace, name)
+			return next(ctx, call)
+		}
+	}
+	apiConfig := coinbasecommerce.NewAPIConfig("key", "2018-03-22")
+	base := coinbasecommerce.NewAPICallContext(apiConfig,
+		coinbasecommerce.APICallContextOptionInterceptors(tracing("a")),
+		coinbasecommerce.APICallContextOptionInterceptors(tracing("b")),
+	)
+	first := base.With(coinbasecommerce.APICallContextOptionInterceptors(tracing("c")))
+	second := base.With(coinbasecommerce.APICallContextOptionInterceptors(tracing("d")))
+
+	tests := []struct {
+		name           string
+		apiCallContext coinbasecommerce.APICallContext
+		wantTrace      string
+	}{
+		{"base", base, "a,b"},
+		{"first copy", first, "a,b,c"},
+		{"second copy", second, "a,b,d"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		invoker := coinbasecommerce.ChainAPIInterceptors(
+			func(ctx context.Context, call *coinbasecommerce.APICall) error { return nil },
+			tt.apiCallContext.Interceptors()...,
+		)
+		invoker(context.Background(), &coinbasecommerce.APICall{})
+		if got := strings.Join(trace, ","); got != tt.wantTrace {
+			t.Errorf("%s: trace = %s, want %s", tt.name, got, tt.wantTrace)
+		}
+	}
+}
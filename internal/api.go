@@ -0,0 +1,395 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// APIResponse represents the body of a response of the Coinbase Commerce API.
+// Data and Pagination should point to the values that the `data` and the
+// `pagination` fields of the response will be decoded into.
+type APIResponse struct {
+	Data       interface{}                  `json:"data,omitempty"`
+	Pagination *coinbasecommerce.Pagination `json:"pagination,omitempty"`
+	Error      *coinbasecommerce.APIError   `json:"error,omitempty"`
+	Warnings   coinbasecommerce.Warnings    `json:"warnings"`
+}
+
+// APIRequestOptions contains data for a request to the Coinbase Commerce API.
+type APIRequestOptions struct {
+	body       []byte
+	idempotent bool
+	check      AttemptCheckFunc
+}
+
+// AttemptCheckFunc checks the outcome of an idempotent request before it's
+// attempted. It returns true if the request must not be sent because its
+// outcome was found otherwise, e.g. the resource that it creates was created
+// by an earlier attempt whose response was lost, in which case it fills the
+// result of the API call itself. Its errors fail the attempt, and they're
+// retried like the errors of the request.
+type AttemptCheckFunc func(ctx context.Context) (done bool, err error)
+
+// APIRequestOptionsFunc represents a function that receives and modifies an
+// APIRequestOptions object.
+type APIRequestOptionsFunc func(*APIRequestOptions)
+
+// APIRequestOptionsJSONBody creates a function that sets a JSON content as
+// the body of an APIRequestOptions object.
+func APIRequestOptionsJSONBody(body []byte) APIRequestOptionsFunc {
+	return func(options *APIRequestOptions) {
+		options.body = body
+	}
+}
+
+// APIRequestOptionsIdempotent creates a function that marks the request of
+// an APIRequestOptions object as idempotent, i.e. safe to retry even if its
+// method is not, e.g. a PUT. A request that isn't idempotent by itself, e.g. a
+// POST that creates a resource, can be made so by a check function that's
+// called before every attempt, which finds the outcome of the earlier
+// attempts; the check function may be equal to nil.
+func APIRequestOptionsIdempotent(check AttemptCheckFunc) APIRequestOptionsFunc {
+	return func(options *APIRequestOptions) {
+		options.idempotent = true
+		options.check = check
+	}
+}
+
+// DoAPIRequest passes the API call through the interceptors of the API call
+// context and then sends its request to the Coinbase Commerce API, retrying
+// it if the retry policy of the API call context allows it. The body of the
+// response is decoded into the APIResponse object.
+func DoAPIRequest(
+	apiCallContext coinbasecommerce.APICallContext,
+	operation, endpointMethod, endpoint string,
+	response *APIResponse,
+	optionFuncs ...APIRequestOptionsFunc,
+) error {
+	options := APIRequestOptions{
+		body:       nil,
+		idempotent: false,
+		check:      nil,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	ctx := apiCallContext.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	call := &coinbasecommerce.APICall{
+		Operation:  operation,
+		Method:     endpointMethod,
+		URL:        endpoint,
+		Header:     make(http.Header),
+		Body:       options.body,
+		Result:     response.Data,
+		Pagination: response.Pagination,
+	}
+	ctx, span := startSpan(apiCallContext.Tracer(), ctx, operation)
+	span.SetAttribute(coinbasecommerce.TraceAttributeOperation, operation)
+	span.SetAttribute(coinbasecommerce.TraceAttributeMethod, endpointMethod)
+	span.SetAttribute(coinbasecommerce.TraceAttributeURL, endpoint)
+
+	invoker := coinbasecommerce.ChainAPIInterceptors(
+		func(ctx context.Context, call *coinbasecommerce.APICall) error {
+			return doAPIRequest(ctx, apiCallContext, call, options)
+		},
+		apiCallContext.Interceptors()...,
+	)
+	startTime := time.Now()
+	err := invoker(ctx, call)
+	endCallSpan(span, call, err)
+
+	if responseMeta := apiCallContext.ResponseMeta(); responseMeta != nil {
+		*responseMeta = coinbasecommerce.NewResponseMeta(call, startTime, time.Since(startTime))
+	}
+
+	response.Warnings = call.Warnings
+	return err
+}
+
+func doAPIRequest(
+	ctx context.Context,
+	apiCallContext coinbasecommerce.APICallContext,
+	call *coinbasecommerce.APICall,
+	options APIRequestOptions,
+) error {
+	response := APIResponse{
+		Data:       call.Result,
+		Pagination: call.Pagination,
+	}
+
+	retryPolicy := apiCallContext.RetryPolicy()
+	maxAttempts := 1
+	if retryPolicy != nil && (options.idempotent || isSafeMethod(call.Method)) {
+		maxAttempts = retryPolicy.MaxAttempts()
+	}
+
+	var httpResponse *http.Response
+	var err, checkErr error
+	attempts, sent := 0, 0
+	for {
+		attempts++
+		httpResponse, err, checkErr = nil, nil, nil
+		if options.check != nil {
+			var done bool
+			if done, checkErr = options.check(ctx); done {
+				return nil
+			}
+			err = checkErr
+		}
+		if err == nil {
+			sent++
+			httpResponse, err = doAttempt(ctx, apiCallContext, call, sent)
+		}
+
+		// the details of the response are those of the last attempt
+		call.Attempts = sent
+		call.StatusCode, call.ResponseHeader = 0, nil
+		if httpResponse != nil {
+			call.StatusCode = httpResponse.StatusCode
+			call.ResponseHeader = httpResponse.Header
+		}
+
+		if attempts >= maxAttempts || !shouldRetry(ctx, retryPolicy, httpResponse, err) {
+			break
+		}
+
+		backoff := retryPolicy.Backoff(attempts, httpResponse)
+		if httpResponse != nil {
+			io.Copy(ioutil.Discard, httpResponse.Body)
+			httpResponse.Body.Close()
+		}
+		if err = Sleep(ctx, backoff); err != nil {
+			checkErr = nil
+			break
+		}
+	}
+	if checkErr != nil {
+		// the error of the check is an error of an API call of its own
+		return WithAttempts(attempts, checkErr)
+	} else if err != nil {
+		return WithAttempts(attempts, localError(ctx, err))
+	}
+	defer httpResponse.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(httpResponse.Body, maxResponseBodySize))
+	if err != nil {
+		return WithAttempts(attempts, localError(ctx, err))
+	}
+	call.ResponseBody = body
+
+	err = json.Unmarshal(body, &response)
+	call.Warnings = response.Warnings
+	if err != nil {
+		if httpResponse.StatusCode < 400 {
+			return WithAttempts(attempts, coinbasecommerce.LocalError{Inner: err})
+		}
+		// e.g. an HTML page from a load balancer
+		response.Error = nil
+	}
+	if response.Error == nil && httpResponse.StatusCode >= 400 {
+		response.Error = &coinbasecommerce.APIError{
+			Type:    apiErrorTypeFromStatusCode(httpResponse.StatusCode),
+			Message: http.StatusText(httpResponse.StatusCode),
+		}
+	}
+	if response.Error != nil {
+		response.Error.StatusCode = httpResponse.StatusCode
+		response.Error.Header = httpResponse.Header
+		response.Error.RequestID = httpResponse.Header.Get(coinbasecommerce.APIHeaderRequestID)
+		response.Error.RawBody = truncate(body, maxAPIErrorRawBodyLength)
+	}
+
+	return WithAttempts(attempts, coinbasecommerce.ReturnAPIErrorAsError(response.Error))
+}
+
+// doAttempt sends the request of the API call once.
+func doAttempt(
+	ctx context.Context,
+	apiCallContext coinbasecommerce.APICallContext,
+	call *coinbasecommerce.APICall,
+	attempt int,
+) (*http.Response, error) {
+	tracer := apiCallContext.Tracer()
+	ctx, span := startSpan(tracer, ctx, call.Operation+" attempt")
+	defer span.End()
+	span.SetAttribute(coinbasecommerce.TraceAttributeAttempt, attempt)
+
+	// the key is obtained before the circuit breaker is consulted, so that a
+	// key that can't be obtained isn't counted as a failure of the API
+	apiKey, err := ResolveAPIKey(ctx, apiCallContext.APIConfig().KeyProvider())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	rateLimiter := apiCallContext.APIConfig().RateLimiter()
+	if rateLimiter != nil {
+		if err := rateLimiter.Wait(ctx, call.Operation); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	circuitBreaker := apiCallContext.APIConfig().CircuitBreaker()
+	var circuitGeneration coinbasecommerce.CircuitGeneration
+	if circuitBreaker != nil {
+		if circuitGeneration, err = circuitBreaker.Allow(); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	header := injectTraceContext(tracer, ctx, call.Header)
+	send := func(apiKey string) (*http.Response, error) {
+		optionFuncs := []CreateAndDoHTTPRequestOptionsFunc{
+			CreateAndDoHTTPRequestOptionsContext(ctx),
+			CreateAndDoHTTPRequestOptionsHeader(header),
+			CreateAndDoHTTPRequestOptionsKeyProvider(coinbasecommerce.StaticKeyProvider(apiKey)),
+		}
+		if call.Body != nil {
+			optionFuncs = append(
+				optionFuncs,
+				CreateAndDoHTTPRequestOptionsJSONBody(bytes.NewReader(call.Body)),
+			)
+		}
+		return CreateAndDoHTTPRequest(apiCallContext, call.Method, call.URL, optionFuncs...)
+	}
+
+	httpResponse, err := send(apiKey)
+	if fallbackKeyProvider := apiCallContext.APIConfig().FallbackKeyProvider(); fallbackKeyProvider != nil &&
+		err == nil && httpResponse.StatusCode == http.StatusUnauthorized {
+		// the primary key may have been rotated, so try the secondary one.
+		// If it can't be obtained, the response to the primary key stands.
+		if fallbackAPIKey, keyErr := ResolveAPIKey(ctx, fallbackKeyProvider); keyErr != nil {
+			span.RecordError(keyErr)
+		} else {
+			io.Copy(ioutil.Discard, httpResponse.Body)
+			httpResponse.Body.Close()
+			httpResponse, err = send(fallbackAPIKey)
+		}
+	}
+	if circuitBreaker != nil {
+		switch {
+		case err != nil && ctx.Err() != nil:
+			circuitBreaker.RecordCanceled(circuitGeneration)
+		case err != nil || httpResponse.StatusCode >= 500:
+			circuitBreaker.RecordFailure(circuitGeneration)
+		default:
+			circuitBreaker.RecordSuccess(circuitGeneration)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if rateLimiter != nil {
+		rateLimiter.Observe(httpResponse)
+	}
+
+	span.SetAttribute(coinbasecommerce.TraceAttributeStatusCode, httpResponse.StatusCode)
+	return httpResponse, nil
+}
+
+const (
+	maxResponseBodySize      = 10 << 20
+	maxAPIErrorRawBodyLength = 2048
+)
+
+// apiErrorTypeFromStatusCode returns the type of the error that the API
+// would have returned along with a response with the status code.
+func apiErrorTypeFromStatusCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return coinbasecommerce.APIErrorTypeInvalidRequest
+	case http.StatusUnauthorized:
+		return coinbasecommerce.APIErrorTypeAuthentication
+	case http.StatusForbidden:
+		return coinbasecommerce.APIErrorTypeAuthorization
+	case http.StatusNotFound:
+		return coinbasecommerce.APIErrorTypeNotFound
+	case http.StatusTooManyRequests:
+		return coinbasecommerce.APIErrorTypeRateLimitExceeded
+	case http.StatusServiceUnavailable:
+		return coinbasecommerce.APIErrorTypeServiceUnavailable
+	}
+	if statusCode >= 500 {
+		return coinbasecommerce.APIErrorTypeInternalServerError
+	}
+	return coinbasecommerce.APIErrorTypeHTTPError
+}
+
+func truncate(body []byte, length int) string {
+	if len(body) <= length {
+		return string(body)
+	}
+	return string(body[:length]) + "..."
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func shouldRetry(
+	ctx context.Context,
+	retryPolicy *coinbasecommerce.RetryPolicy,
+	httpResponse *http.Response,
+	err error,
+) bool {
+	if err != nil {
+		// the caller gave up on the call if its context is done, and the
+		// errors of the key provider are final. The others, e.g. an open
+		// circuit or an untrusted certificate, are final unless they're
+		// transient failures of the network.
+		var keyProviderError KeyProviderError
+		return ctx.Err() == nil && !errors.As(err, &keyProviderError) &&
+			coinbasecommerce.IsRetryable(err)
+	}
+	return retryPolicy.IsRetryableStatus(httpResponse.StatusCode)
+}
+
+// localError wraps the error of an API call in a LocalError. If the context
+// of the call is done, the error is wrapped in a ContextError first, since
+// it's the context of the call, rather than the error, that tells whether the
+// caller gave up on it.
+func localError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		err = coinbasecommerce.ContextError{Inner: err}
+	}
+	return coinbasecommerce.LocalError{Inner: err}
+}
+
+// Sleep pauses for the duration, or until the context is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithAttempts wraps the error in a RetryError if it is not equal to nil and
+// the request was attempted more than once.
+func WithAttempts(attempts int, err error) error {
+	if err == nil || attempts < 2 {
+		return err
+	}
+	return coinbasecommerce.RetryError{Attempts: attempts, Inner: err}
+}
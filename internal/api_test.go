@@ -0,0 +1,458 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/internal"
+)
+
+func TestDoAPIRequestKeyProviders(t *testing.T) {
+	unavailable := coinbasecommerce.KeyProviderFunc(func(ctx context.Context) (string, error) {
+		return "", coinbasecommerce.ErrInvalidAPIKey
+	})
+	tests := []struct {
+		name         string
+		keyProvider  coinbasecommerce.KeyProvider
+		fallback     coinbasecommerce.KeyProvider
+		wantRequests int32
+		wantErr      error
+	}{
+		{
+			name:         "primary key",
+			keyProvider:  coinbasecommerce.StaticKeyProvider("valid"),
+			wantRequests: 1,
+		},
+		{
+			name:         "unavailable primary key",
+			keyProvider:  unavailable,
+			wantRequests: 0,
+			wantErr:      coinbasecommerce.ErrInvalidAPIKey,
+		},
+		{
+			name:         "unset environment variable",
+			keyProvider:  coinbasecommerce.EnvKeyProvider("COINBASE_COMMERCE_TEST_UNSET_API_KEY"),
+			wantRequests: 0,
+			wantErr:      coinbasecommerce.ErrInvalidAPIKey,
+		},
+		{
+			name:         "fallback key",
+			keyProvider:  coinbasecommerce.StaticKeyProvider("rotated"),
+			fallback:     coinbasecommerce.StaticKeyProvider("valid"),
+			wantRequests: 2,
+		},
+		{
+			name:         "unavailable fallback key",
+			keyProvider:  coinbasecommerce.StaticKeyProvider("rotated"),
+			fallback:     unavailable,
+			wantRequests: 1,
+			wantErr:      coinbasecommerce.ErrAPIAuthentication,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.Header().Set("Content-Type", "application/json")
+				if r.Header.Get(coinbasecommerce.APIHeaderAPIKey) != "valid" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error": {"type": "authentication_error", "message": "No such API key."}}`))
+					return
+				}
+				w.Write([]byte(`{"data": {}}`))
+			}))
+			defer server.Close()
+
+			breaker := coinbasecommerce.NewCircuitBreaker(coinbasecommerce.CircuitBreakerOptionFailureThreshold(1))
+			optionFuncs := []coinbasecommerce.APIConfigOptionFunc{
+				coinbasecommerce.APIConfigOptionBaseURL(server.URL),
+				coinbasecommerce.APIConfigOptionKeyProvider(test.keyProvider),
+				coinbasecommerce.APIConfigOptionCircuitBreaker(breaker),
+			}
+			if test.fallback != nil {
+				optionFuncs = append(optionFuncs, coinbasecommerce.APIConfigOptionFallbackKeyProvider(test.fallback))
+			}
+			apiCallContext := coinbasecommerce.NewAPICallContext(
+				coinbasecommerce.NewAPIConfig("", "2018-03-22", optionFuncs...),
+				coinbasecommerce.APICallContextOptionRetryPolicy(coinbasecommerce.NewRetryPolicy(
+					coinbasecommerce.RetryPolicyOptionMaxAttempts(3),
+					coinbasecommerce.RetryPolicyOptionBackoff(time.Millisecond, time.Millisecond),
+				)),
+			)
+
+			var data struct{}
+			err := internal.DoAPIRequest(apiCallContext, "charges.Get", http.MethodGet,
+				server.URL+"/charges/ABC", &internal.APIResponse{Data: &data})
+
+			if test.wantErr == nil && err != nil || test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("DoAPIRequest() = %v, want %v", err, test.wantErr)
+			}
+			var retryError coinbasecommerce.RetryError
+			if errors.As(err, &retryError) {
+				t.Errorf("DoAPIRequest() = %v, want no retries", err)
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+			if state := breaker.State(); state != coinbasecommerce.CircuitStateClosed {
+				t.Errorf("circuit state = %s, want closed", state)
+			}
+		})
+	}
+}
+
+func TestDoAPIRequestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		optionFuncs  []internal.APIRequestOptionsFunc
+		statusCodes  []int
+		wantRequests int32
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "safe method until it succeeds",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantRequests: 2,
+		},
+		{
+			name:         "safe method until the attempts run out",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantRequests: 3,
+			wantAttempts: 3,
+			wantErr:      coinbasecommerce.ErrAPIInternalServerError,
+		},
+		{
+			name:         "rate limited safe method",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusTooManyRequests, http.StatusOK},
+			wantRequests: 2,
+		},
+		{
+			name:         "final status",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusNotFound},
+			wantRequests: 1,
+			wantErr:      coinbasecommerce.ErrAPINotFound,
+		},
+		{
+			name:         "unsafe method",
+			method:       http.MethodPost,
+			statusCodes:  []int{http.StatusServiceUnavailable},
+			wantRequests: 1,
+			wantErr:      coinbasecommerce.ErrAPIServiceUnavailable,
+		},
+		{
+			name:         "idempotent unsafe method",
+			method:       http.MethodPut,
+			optionFuncs:  []internal.APIRequestOptionsFunc{internal.APIRequestOptionsIdempotent(nil)},
+			statusCodes:  []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			wantRequests: 3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := atomic.AddInt32(&requests, 1) - 1
+				statusCode := test.statusCodes[len(test.statusCodes)-1]
+				if int(i) < len(test.statusCodes) {
+					statusCode = test.statusCodes[i]
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(statusCode)
+				w.Write([]byte(`{"data": {}}`))
+			}))
+			defer server.Close()
+
+			var data struct{}
+			err := internal.DoAPIRequest(newRetryingAPICallContext(server.URL), "test.Call", test.method,
+				server.URL+"/test", &internal.APIResponse{Data: &data}, test.optionFuncs...)
+
+			if test.wantErr == nil && err != nil || test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("DoAPIRequest() = %v, want %v", err, test.wantErr)
+			}
+			var retryError coinbasecommerce.RetryError
+			if errors.As(err, &retryError) != (test.wantAttempts != 0) || retryError.Attempts != test.wantAttempts {
+				t.Errorf("DoAPIRequest() = %v, want a RetryError after %d attempts", err, test.wantAttempts)
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestDoAPIRequestAPIErrors(t *testing.T) {
+	longBody := strings.Repeat("x", 3000)
+	tests := []struct {
+		name        string
+		statusCode  int
+		contentType string
+		body        string
+		wantType    string
+		wantMessage string
+		wantRawBody string
+	}{
+		{
+			name:        "error of the API",
+			statusCode:  http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"error": {"type": "invalid_request", "message": "Invalid pricing type"}}`,
+			wantType:    coinbasecommerce.APIErrorTypeInvalidRequest,
+			wantMessage: "Invalid pricing type",
+			wantRawBody: `{"error": {"type": "invalid_request", "message": "Invalid pricing type"}}`,
+		},
+		{
+			name:        "JSON without an error",
+			statusCode:  http.StatusNotFound,
+			contentType: "application/json",
+			body:        `{}`,
+			wantType:    coinbasecommerce.APIErrorTypeNotFound,
+			wantMessage: "Not Found",
+			wantRawBody: `{}`,
+		},
+		{
+			name:        "HTML from a load balancer",
+			statusCode:  http.StatusBadGateway,
+			contentType: "text/html",
+			body:        "<html><body>502 Bad Gateway</body></html>",
+			wantType:    coinbasecommerce.APIErrorTypeInternalServerError,
+			wantMessage: "Bad Gateway",
+			wantRawBody: "<html><body>502 Bad Gateway</body></html>",
+		},
+		{
+			name:        "plain text while unavailable",
+			statusCode:  http.StatusServiceUnavailable,
+			contentType: "text/plain",
+			body:        "upstream connect error",
+			wantType:    coinbasecommerce.APIErrorTypeServiceUnavailable,
+			wantMessage: "Service Unavailable",
+			wantRawBody: "upstream connect error",
+		},
+		{
+			name:        "no body with an unknown status",
+			statusCode:  http.StatusTeapot,
+			wantType:    coinbasecommerce.APIErrorTypeHTTPError,
+			wantMessage: "I'm a teapot",
+		},
+		{
+			name:        "long body",
+			statusCode:  http.StatusInternalServerError,
+			contentType: "text/plain",
+			body:        longBody,
+			wantType:    coinbasecommerce.APIErrorTypeInternalServerError,
+			wantMessage: "Internal Server Error",
+			wantRawBody: longBody[:2048] + "...",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.contentType != "" {
+					w.Header().Set("Content-Type", test.contentType)
+				}
+				w.Header().Set(coinbasecommerce.APIHeaderRequestID, "request-id")
+				w.WriteHeader(test.statusCode)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			apiCallContext := coinbasecommerce.NewAPICallContext(
+				coinbasecommerce.NewAPIConfig("key", "2018-03-22", coinbasecommerce.APIConfigOptionBaseURL(server.URL)))
+			var data struct{}
+			err := internal.DoAPIRequest(apiCallContext, "test.Call", http.MethodGet,
+				server.URL+"/test", &internal.APIResponse{Data: &data})
+
+			var apiError coinbasecommerce.APIError
+			if !errors.As(err, &apiError) {
+				t.Fatalf("DoAPIRequest() = %v, want an APIError", err)
+			}
+			var apiErrorPointer *coinbasecommerce.APIError
+			if !errors.As(err, &apiErrorPointer) || apiErrorPointer.Type != apiError.Type {
+				t.Errorf("errors.As(%v, **APIError) = %v, want the same error", err, apiErrorPointer)
+			}
+			if apiError.Type != test.wantType || apiError.Message != test.wantMessage {
+				t.Errorf("APIError = %s (%s), want %s (%s)", apiError.Type, apiError.Message, test.wantType, test.wantMessage)
+			}
+			if apiError.StatusCode != test.statusCode {
+				t.Errorf("StatusCode = %d, want %d", apiError.StatusCode, test.statusCode)
+			}
+			if apiError.RequestID != "request-id" {
+				t.Errorf("RequestID = %q, want %q", apiError.RequestID, "request-id")
+			}
+			if apiError.RawBody != test.wantRawBody {
+				t.Errorf("RawBody = %q, want %q", apiError.RawBody, test.wantRawBody)
+			}
+		})
+	}
+}
+
+func TestDoAPIRequestInvalidBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	apiCallContext := coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig("key", "2018-03-22", coinbasecommerce.APIConfigOptionBaseURL(server.URL)))
+	var data struct{}
+	err := internal.DoAPIRequest(apiCallContext, "test.Call", http.MethodGet,
+		server.URL+"/test", &internal.APIResponse{Data: &data})
+
+	var localError coinbasecommerce.LocalError
+	var apiError coinbasecommerce.APIError
+	if !errors.As(err, &localError) || errors.As(err, &apiError) {
+		t.Errorf("DoAPIRequest() = %v, want a LocalError", err)
+	}
+}
+
+func TestDoAPIRequestFinalTransportErrors(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	// the handshakes with the untrusted certificate fail by design
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+	}{
+		{"untrusted certificate", server.URL + "/test"},
+		{"unsupported scheme", "ftp" + strings.TrimPrefix(server.URL, "https") + "/test"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var data struct{}
+			err := internal.DoAPIRequest(newRetryingAPICallContext(server.URL), "test.Call", http.MethodGet,
+				test.endpoint, &internal.APIResponse{Data: &data})
+
+			var localError coinbasecommerce.LocalError
+			if !errors.As(err, &localError) {
+				t.Fatalf("DoAPIRequest() = %v, want a LocalError", err)
+			}
+			var retryError coinbasecommerce.RetryError
+			if errors.As(err, &retryError) {
+				t.Errorf("DoAPIRequest() = %v, want no retries", err)
+			}
+		})
+	}
+}
+
+func TestDoAPIRequestAttemptCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       []error
+		wantChecks   int
+		wantRequests int32
+		wantResult   string
+		wantErr      error
+	}{
+		{
+			name:         "found before the first attempt",
+			checks:       []error{errFound},
+			wantChecks:   1,
+			wantRequests: 0,
+			wantResult:   "found",
+		},
+		{
+			name:         "found after a failed attempt",
+			checks:       []error{nil, errFound},
+			wantChecks:   2,
+			wantRequests: 1,
+			wantResult:   "found",
+		},
+		{
+			name:         "sent after a failed check",
+			checks:       []error{coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeServiceUnavailable, StatusCode: 503}, nil},
+			wantChecks:   2,
+			wantRequests: 1,
+			wantResult:   "created",
+		},
+		{
+			name:         "final error of the check",
+			checks:       []error{coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeNotFound, StatusCode: 404}},
+			wantChecks:   1,
+			wantRequests: 0,
+			wantErr:      coinbasecommerce.ErrAPINotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if atomic.AddInt32(&requests, 1) == 1 && len(test.checks) > 1 && test.checks[1] == errFound {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte(`{"error": {"type": "service_unavailable", "message": "try again"}}`))
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"data": {"name": "created"}}`))
+			}))
+			defer server.Close()
+
+			var data struct {
+				Name string `json:"name"`
+			}
+			checks := 0
+			check := func(ctx context.Context) (bool, error) {
+				err := test.checks[checks]
+				checks++
+				if err == errFound {
+					data.Name = "found"
+					return true, nil
+				}
+				return false, err
+			}
+			err := internal.DoAPIRequest(newRetryingAPICallContext(server.URL), "test.Create", http.MethodPost,
+				server.URL+"/test", &internal.APIResponse{Data: &data},
+				internal.APIRequestOptionsIdempotent(check))
+
+			if test.wantErr == nil && err != nil || test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("DoAPIRequest() = %v, want %v", err, test.wantErr)
+			}
+			if checks != test.wantChecks {
+				t.Errorf("checks = %d, want %d", checks, test.wantChecks)
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+			if data.Name != test.wantResult {
+				t.Errorf("result = %q, want %q", data.Name, test.wantResult)
+			}
+		})
+	}
+}
+
+// errFound makes the check of TestDoAPIRequestAttemptCheck find the outcome
+// of the request.
+var errFound = errors.New("found")
+
+// newRetryingAPICallContext creates an API call context for the server at the
+// base URL, whose requests are attempted at most 3 times without backoff.
+func newRetryingAPICallContext(baseURL string) coinbasecommerce.APICallContext {
+	return coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig("key", "2018-03-22", coinbasecommerce.APIConfigOptionBaseURL(baseURL)),
+		coinbasecommerce.APICallContextOptionRetryPolicy(coinbasecommerce.NewRetryPolicy(
+			coinbasecommerce.RetryPolicyOptionMaxAttempts(3),
+			coinbasecommerce.RetryPolicyOptionBackoff(0, 0),
+		)),
+	)
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// MakeEndpoint creates the URL of a Coinbase Commerce API endpoint by
+// appending the formatted endpoint path to the base URL of the API
+// configuration. Each of the arguments is path-escaped before it is
+// substituted into the endpoint path format.
+func MakeEndpoint(
+	apiConfig *coinbasecommerce.APIConfig,
+	endpointPathFmt string,
+	args ...string,
+) string {
+	escapedArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		escapedArgs[i] = url.PathEscape(arg)
+	}
+	return apiConfig.BaseURL() + fmt.Sprintf(endpointPathFmt, escapedArgs...)
+}
+
+// AppendQueryString appends the query string to the endpoint, if the query
+// string is not empty.
+func AppendQueryString(endpoint, queryString string) string {
+	if queryString == "" {
+		return endpoint
+	}
+	return endpoint + "?" + queryString
+}
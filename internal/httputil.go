@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -12,6 +14,9 @@ type CreateAndDoHTTPRequestOptions struct {
 	hasBody         bool
 	body            io.Reader
 	bodyContentType string
+	header          http.Header
+	context         context.Context
+	keyProvider     coinbasecommerce.KeyProvider
 }
 
 // CreateAndDoHTTPRequestOptionsFunc represents a function that receives and
@@ -37,6 +42,59 @@ func CreateAndDoHTTPRequestOptionsJSONBody(body io.Reader) CreateAndDoHTTPReques
 	return CreateAndDoHTTPRequestOptionsBody(body, "application/json")
 }
 
+// CreateAndDoHTTPRequestOptionsHeader creates a function that sets the
+// additional headers of the CreateAndDoHTTPRequestOptions object.
+func CreateAndDoHTTPRequestOptionsHeader(header http.Header) CreateAndDoHTTPRequestOptionsFunc {
+	return func(options *CreateAndDoHTTPRequestOptions) {
+		options.header = header
+	}
+}
+
+// CreateAndDoHTTPRequestOptionsContext creates a function that sets the
+// context of the CreateAndDoHTTPRequestOptions object, which overrides the
+// context of the API call context.
+func CreateAndDoHTTPRequestOptionsContext(ctx context.Context) CreateAndDoHTTPRequestOptionsFunc {
+	return func(options *CreateAndDoHTTPRequestOptions) {
+		options.context = ctx
+	}
+}
+
+// CreateAndDoHTTPRequestOptionsKeyProvider creates a function that sets the
+// key provider of the CreateAndDoHTTPRequestOptions object, which overrides
+// the key provider of the API configuration.
+func CreateAndDoHTTPRequestOptionsKeyProvider(
+	keyProvider coinbasecommerce.KeyProvider,
+) CreateAndDoHTTPRequestOptionsFunc {
+	return func(options *CreateAndDoHTTPRequestOptions) {
+		options.keyProvider = keyProvider
+	}
+}
+
+// KeyProviderError is an error of the key provider of a request. No request
+// was sent, so it says nothing about the health of the API, and it's final
+// since the request would fail the same way if it was attempted again.
+type KeyProviderError struct {
+	Inner error
+}
+
+func (e KeyProviderError) Error() string {
+	return fmt.Sprintf("api key unavailable: %v", e.Inner)
+}
+
+func (e KeyProviderError) Unwrap() error {
+	return e.Inner
+}
+
+// ResolveAPIKey obtains an API key from the key provider. Its errors are
+// wrapped in a KeyProviderError.
+func ResolveAPIKey(ctx context.Context, keyProvider coinbasecommerce.KeyProvider) (string, error) {
+	apiKey, err := keyProvider.APIKey(ctx)
+	if err != nil {
+		return "", KeyProviderError{Inner: err}
+	}
+	return apiKey, nil
+}
+
 // CreateAndDoHTTPRequest creates a HTTP request, executes it, and then
 // returns its response.
 func CreateAndDoHTTPRequest(
@@ -48,39 +106,43 @@ func CreateAndDoHTTPRequest(
 		hasBody:         false,
 		body:            nil,
 		bodyContentType: "",
+		header:          nil,
+		context:         apiCallContext.Context(),
+		keyProvider:     apiCallContext.APIConfig().KeyProvider(),
 	}
 	for _, optionFunc := range optionFuncs {
 		optionFunc(&options)
 	}
 
-	var httpRequest *http.Request
-	var err error
-
-	if ctx := apiCallContext.Context(); ctx != nil {
-		httpRequest, err = http.NewRequestWithContext(
-			ctx,
-			endpointMethod,
-			endpoint,
-			options.body,
-		)
-	} else {
-		httpRequest, err = http.NewRequest(
-			endpointMethod,
-			endpoint,
-			options.body,
-		)
+	ctx := options.context
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	apiKey, err := ResolveAPIKey(ctx, options.keyProvider)
 	if err != nil {
 		return nil, err
 	}
 
+	httpRequest, err := http.NewRequestWithContext(
+		ctx,
+		endpointMethod,
+		endpoint,
+		options.body,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range options.header {
+		httpRequest.Header[key] = append([]string(nil), values...)
+	}
 	if options.hasBody {
 		httpRequest.Header.Set("Content-Type", options.bodyContentType)
 	}
 
 	httpRequest.Header.Set(
 		coinbasecommerce.APIHeaderAPIKey,
-		apiCallContext.APIConfig().APIKey(),
+		apiKey,
 	)
 	httpRequest.Header.Set(
 		coinbasecommerce.APIHeaderVersion,
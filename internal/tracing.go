@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// startSpan starts a span using the tracer, if it's not equal to nil.
+func startSpan(
+	tracer coinbasecommerce.Tracer,
+	ctx context.Context,
+	name string,
+) (context.Context, coinbasecommerce.Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+// injectTraceContext returns the header with the trace context of the span
+// in the context, if the tracer can propagate it.
+func injectTraceContext(
+	tracer coinbasecommerce.Tracer,
+	ctx context.Context,
+	header http.Header,
+) http.Header {
+	propagator, ok := tracer.(coinbasecommerce.TracePropagator)
+	if !ok {
+		return header
+	}
+	header = header.Clone()
+	propagator.Inject(ctx, header)
+	return header
+}
+
+// endCallSpan records the outcome of the API call on its span and ends it.
+func endCallSpan(span coinbasecommerce.Span, call *coinbasecommerce.APICall, err error) {
+	span.SetAttribute(coinbasecommerce.TraceAttributeAttempts, call.Attempts)
+	span.SetAttribute(coinbasecommerce.TraceAttributeWarnings, len(call.Warnings))
+	if call.StatusCode != 0 {
+		span.SetAttribute(coinbasecommerce.TraceAttributeStatusCode, call.StatusCode)
+	}
+	if err != nil {
+		var apiError coinbasecommerce.APIError
+		if errors.As(err, &apiError) {
+			span.SetAttribute(coinbasecommerce.TraceAttributeErrorType, apiError.Type)
+		} else {
+			span.SetAttribute(coinbasecommerce.TraceAttributeErrorType, coinbasecommerce.MetricsErrorTypeLocal)
+		}
+		span.RecordError(err)
+	}
+	span.End()
+}
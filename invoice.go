@@ -0,0 +1,35 @@
+package coinbasecommerce
+
+import "time"
+
+// InvoiceStatus represents the status of an invoice.
+type InvoiceStatus string
+
+// InvoiceStatus constants. These are the possible statuses of an invoice.
+const (
+	InvoiceStatusOpen           InvoiceStatus = "OPEN"
+	InvoiceStatusViewed         InvoiceStatus = "VIEWED"
+	InvoiceStatusPaymentPending InvoiceStatus = "PAYMENT_PENDING"
+	InvoiceStatusPaid           InvoiceStatus = "PAID"
+	InvoiceStatusUnresolved     InvoiceStatus = "UNRESOLVED"
+	InvoiceStatusResolved       InvoiceStatus = "RESOLVED"
+	InvoiceStatusVoid           InvoiceStatus = "VOID"
+)
+
+// Invoice contains full details about an invoice. The charge of an invoice
+// is only set after the customer has chosen how to pay it.
+type Invoice struct {
+	ID            string        `json:"id"`
+	Resource      string        `json:"resource"`
+	Code          string        `json:"code"`
+	Status        InvoiceStatus `json:"status"`
+	BusinessName  string        `json:"business_name"`
+	CustomerName  string        `json:"customer_name"`
+	CustomerEmail string        `json:"customer_email"`
+	Memo          string        `json:"memo"`
+	LocalPrice    Money         `json:"local_price"`
+	HostedURL     string        `json:"hosted_url"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Charge        *Charge       `json:"charge,omitempty"`
+}
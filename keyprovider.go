@@ -0,0 +1,115 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyProvider provides the API key that's sent along with every request to
+// the Coinbase Commerce API. It's consulted once per request, so the key can
+// be rotated without restarting the application.
+type KeyProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// StaticKeyProvider is a KeyProvider that always provides the same key.
+type StaticKeyProvider string
+
+// APIKey returns the key.
+func (p StaticKeyProvider) APIKey(ctx context.Context) (string, error) {
+	return string(p), nil
+}
+
+// EnvKeyProvider is a KeyProvider that provides the value of the environment
+// variable with its name, e.g. "COINBASE_COMMERCE_API_KEY".
+type EnvKeyProvider string
+
+// APIKey returns the value of the environment variable.
+func (p EnvKeyProvider) APIKey(ctx context.Context) (string, error) {
+	apiKey := strings.TrimSpace(os.Getenv(string(p)))
+	if apiKey == "" {
+		return "", fmt.Errorf("%w: %s is not set", ErrInvalidAPIKey, string(p))
+	}
+	return apiKey, nil
+}
+
+// KeyProviderFunc is a function that can be used as a KeyProvider.
+type KeyProviderFunc func(ctx context.Context) (string, error)
+
+// APIKey calls the function.
+func (f KeyProviderFunc) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// FileKeyProvider is a KeyProvider that provides the contents of a file,
+// without the surrounding whitespace. The file is read again at most once per
+// check interval, and the key is replaced when the contents have changed. If
+// the file can't be read or is empty, e.g. while it's being replaced, the last
+// key that was read is provided until it can. It's safe for concurrent use by
+// multiple goroutines.
+type FileKeyProvider struct {
+	path          string
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	apiKey    string
+	sum       [sha256.Size]byte
+	checkedAt time.Time
+}
+
+// NewFileKeyProvider creates a new file key provider. The file is checked for
+// changes at most once per check interval, e.g. every second.
+func NewFileKeyProvider(path string, checkInterval time.Duration) *FileKeyProvider {
+	if path == "" {
+		panic("path cannot be empty")
+	}
+	return &FileKeyProvider{path: path, checkInterval: checkInterval}
+}
+
+// APIKey returns the contents of the file.
+func (p *FileKeyProvider) APIKey(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.apiKey != "" && now.Sub(p.checkedAt) < p.checkInterval {
+		return p.apiKey, nil
+	}
+
+	// the contents are compared rather than the modification time and size,
+	// which don't change if a key of the same length is written within the
+	// resolution of the modification time
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		if p.apiKey != "" {
+			p.checkedAt = now
+			return p.apiKey, nil
+		}
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if p.apiKey != "" && sum == p.sum {
+		p.checkedAt = now
+		return p.apiKey, nil
+	}
+
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		if p.apiKey != "" {
+			p.checkedAt = now
+			return p.apiKey, nil
+		}
+		return "", fmt.Errorf("%w: %s is empty", ErrInvalidAPIKey, p.path)
+	}
+
+	p.apiKey = apiKey
+	p.sum = sum
+	p.checkedAt = now
+	return p.apiKey, nil
+}
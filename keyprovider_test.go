@@ -0,0 +1,98 @@
+package coinbasecommerce_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+func TestFileKeyProviderRotation(t *testing.T) {
+	path, cleanup := writeTempFile(t, "api-key", "key-1\n")
+	defer cleanup()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := coinbasecommerce.NewFileKeyProvider(path, 0)
+
+	steps := []struct {
+		name   string
+		rotate func() error
+		want   string
+	}{
+		{"first read", func() error { return nil }, "key-1"},
+		{"key of the same length within the same tick", func() error {
+			if err := ioutil.WriteFile(path, []byte("key-2\n"), 0600); err != nil {
+				return err
+			}
+			return os.Chtimes(path, info.ModTime(), info.ModTime())
+		}, "key-2"},
+		{"file being replaced", func() error { return os.Remove(path) }, "key-2"},
+		{"empty file", func() error { return ioutil.WriteFile(path, nil, 0600) }, "key-2"},
+		{"file replaced", func() error {
+			newPath := filepath.Join(filepath.Dir(path), "api-key.new")
+			if err := ioutil.WriteFile(newPath, []byte(" key-3 "), 0600); err != nil {
+				return err
+			}
+			return os.Rename(newPath, path)
+		}, "key-3"},
+	}
+	for _, step := range steps {
+		if err := step.rotate(); err != nil {
+			t.Fatal(err)
+		}
+		apiKey, err := provider.APIKey(context.Background())
+		if err != nil || apiKey != step.want {
+			t.Errorf("%s: APIKey() = %q, %v, want %q", step.name, apiKey, err, step.want)
+		}
+	}
+}
+
+func TestFileKeyProviderWithoutKey(t *testing.T) {
+	path, cleanup := writeTempFile(t, "api-key", " \n")
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		path         string
+		wantNotExist bool
+	}{
+		{"empty file", path, false},
+		{"no file", filepath.Join(filepath.Dir(path), "missing"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := coinbasecommerce.NewFileKeyProvider(tt.path, 0).APIKey(context.Background())
+			if err == nil {
+				t.Fatal("APIKey() error = nil, want an error")
+			}
+			if errors.Is(err, os.ErrNotExist) != tt.wantNotExist {
+				t.Errorf("APIKey() error = %v, want os.ErrNotExist %v", err, tt.wantNotExist)
+			}
+			if !tt.wantNotExist && !errors.Is(err, coinbasecommerce.ErrInvalidAPIKey) {
+				t.Errorf("APIKey() error = %v, want %v", err, coinbasecommerce.ErrInvalidAPIKey)
+			}
+		})
+	}
+}
+
+func TestFileKeyProviderCheckInterval(t *testing.T) {
+	path, cleanup := writeTempFile(t, "api-key", "key-1")
+	defer cleanup()
+	provider := coinbasecommerce.NewFileKeyProvider(path, time.Hour)
+
+	for _, content := range []string{"key-1", "key-2"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if apiKey, err := provider.APIKey(context.Background()); err != nil || apiKey != "key-1" {
+			t.Errorf("APIKey() after writing %q = %q, %v, want %q until the interval passes", content, apiKey, err, "key-1")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Logger represents a structured logger that accepts alternating keys and
+// values, e.g. a *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// RedactedValue replaces the values that must not be logged.
+const RedactedValue = "[REDACTED]"
+
+// LoggingOptions contains options for the logging of API calls.
+type LoggingOptions struct {
+	logBodies    bool
+	maskMetadata bool
+	maskedFields map[string]bool
+}
+
+// LoggingOptionFunc represents a function that can modify the contents of
+// the LoggingOptions.
+type LoggingOptionFunc func(*LoggingOptions)
+
+// LoggingOptionBodies makes the logger log the headers and the bodies of the
+// requests and the responses at debug level.
+func LoggingOptionBodies() LoggingOptionFunc {
+	return func(options *LoggingOptions) {
+		options.logBodies = true
+	}
+}
+
+// LoggingOptionMaskMetadata sets whether the values of the `metadata` fields
+// in the logged bodies are masked. They're masked by default.
+func LoggingOptionMaskMetadata(maskMetadata bool) LoggingOptionFunc {
+	return func(options *LoggingOptions) {
+		options.maskMetadata = maskMetadata
+	}
+}
+
+// LoggingOptionMaskedFields sets the names of the fields whose values are
+// masked anywhere in the logged bodies. By default, the fields that contain
+// the information that's requested from customers are masked, i.e. "email",
+// "customer_email" and "customer_name".
+func LoggingOptionMaskedFields(fields ...string) LoggingOptionFunc {
+	return func(options *LoggingOptions) {
+		options.maskedFields = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			options.maskedFields[field] = true
+		}
+	}
+}
+
+// NewLoggingInterceptor creates an interceptor that logs every API call: its
+// operation, method, URL, status code, latency, attempts, warnings and error.
+// Successful calls are logged at info level, calls that the API rejected at
+// warn level and calls that failed locally at error level. The API key is
+// never logged.
+func NewLoggingInterceptor(logger Logger, optionFuncs ...LoggingOptionFunc) APIInterceptor {
+	if logger == nil {
+		panic("logger cannot be equal to nil")
+	}
+
+	options := LoggingOptions{
+		logBodies:    false,
+		maskMetadata: true,
+		maskedFields: map[string]bool{
+			"email":          true,
+			"customer_email": true,
+			"customer_name":  true,
+		},
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&options)
+	}
+
+	return func(ctx context.Context, call *APICall, next APIInvoker) error {
+		start := time.Now()
+		err := next(ctx, call)
+		latency := time.Since(start)
+
+		args := []interface{}{
+			"operation", call.Operation,
+			"method", call.Method,
+			"url", call.URL,
+			"status", call.StatusCode,
+			"latency", latency,
+			"attempts", call.Attempts,
+		}
+		if len(call.Warnings) != 0 {
+			args = append(args, "warnings", []string(call.Warnings))
+		}
+
+		var apiError APIError
+		switch {
+		case err == nil:
+			logger.Info("coinbase commerce api call succeeded", args...)
+		case errors.As(err, &apiError):
+			args = append(args, "error_type", apiError.Type, "error", err.Error())
+			if apiError.RequestID != "" {
+				args = append(args, "request_id", apiError.RequestID)
+			}
+			logger.Warn("coinbase commerce api call failed", args...)
+		default:
+			args = append(args, "error", err.Error())
+			logger.Error("coinbase commerce api call failed", args...)
+		}
+
+		if options.logBodies {
+			logger.Debug(
+				"coinbase commerce api call bodies",
+				"operation", call.Operation,
+				"request_header", redactHeader(call.Header),
+				"request_body", options.maskBody(call.Body),
+				"response_header", redactHeader(call.ResponseHeader),
+				"response_body", options.maskBody(call.ResponseBody),
+			)
+		}
+
+		return err
+	}
+}
+
+// APICallContextOptionLogger appends a logging interceptor to the chain that
+// the API call will be passed through. See NewLoggingInterceptor.
+func APICallContextOptionLogger(logger Logger, optionFuncs ...LoggingOptionFunc) APICallContextOptionFunc {
+	return APICallContextOptionInterceptors(NewLoggingInterceptor(logger, optionFuncs...))
+}
+
+func redactHeader(header http.Header) map[string][]string {
+	redacted := make(map[string][]string, len(header))
+	for key, values := range header {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(APIHeaderAPIKey) {
+			values = []string{RedactedValue}
+		}
+		redacted[key] = values
+	}
+	return redacted
+}
+
+func (options *LoggingOptions) maskBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		// the body is only logged if it can be masked
+		return RedactedValue
+	}
+	masked, err := json.Marshal(options.mask(value, false))
+	if err != nil {
+		return RedactedValue
+	}
+	return string(masked)
+}
+
+func (options *LoggingOptions) mask(value interface{}, inMetadata bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch {
+			case options.maskedFields[key]:
+				v[key] = RedactedValue
+			case key == "metadata" && options.maskMetadata:
+				v[key] = options.mask(field, true)
+			case inMetadata:
+				v[key] = RedactedValue
+			default:
+				v[key] = options.mask(field, false)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = options.mask(item, inMetadata)
+		}
+		return v
+	default:
+		if inMetadata {
+			return RedactedValue
+		}
+		return v
+	}
+}
@@ -0,0 +1,143 @@
+package coinbasecommerce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+)
+
+const (
+	testLoggedAPIKey   = "secret-api-key"
+	testLoggedEmail    = "alice@example.com"
+	testLoggedName     = "Alice Liddell"
+	testLoggedMetadata = "customer-42"
+)
+
+func TestLoggingInterceptorRedactsSecrets(t *testing.T) {
+	requestBody := fmt.Sprintf(`{"name":"Order","customer_email":%q,"metadata":{"customer_id":%q}}`,
+		testLoggedEmail, testLoggedMetadata)
+	responseBody := fmt.Sprintf(`{"data":{"code":"ABC","customer_name":%q,"email":%q,"metadata":{"customer_id":%q}}}`,
+		testLoggedName, testLoggedEmail, testLoggedMetadata)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel string
+	}{
+		{"succeeded", nil, "info"},
+		{"rejected", coinbasecommerce.APIError{Type: coinbasecommerce.APIErrorTypeInvalidRequest, RequestID: "request-id"}, "warn"},
+		{"failed locally", coinbasecommerce.LocalError{Inner: errors.New("connection refused")}, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			interceptor := coinbasecommerce.NewLoggingInterceptor(logger, coinbasecommerce.LoggingOptionBodies())
+
+			header := make(http.Header)
+			header.Set(coinbasecommerce.APIHeaderAPIKey, testLoggedAPIKey)
+			// set by an interceptor without canonicalizing it
+			header["x-cc-api-key"] = []string{testLoggedAPIKey}
+			call := &coinbasecommerce.APICall{
+				Operation: "charges.Create",
+				Method:    http.MethodPost,
+				URL:       "https://api.commerce.coinbase.com/charges",
+				Header:    header,
+				Body:      []byte(requestBody),
+			}
+			err := interceptor(context.Background(), call, func(ctx context.Context, call *coinbasecommerce.APICall) error {
+				call.StatusCode = http.StatusCreated
+				call.ResponseHeader = http.Header{"Content-Type": {"application/json"}}
+				call.ResponseBody = []byte(responseBody)
+				return tt.err
+			})
+			if (err == nil) != (tt.err == nil) {
+				t.Errorf("interceptor() = %v, want %v", err, tt.err)
+			}
+
+			if got := logger.levels(); got != tt.wantLevel+",debug" {
+				t.Errorf("logged levels = %s, want %s,debug", got, tt.wantLevel)
+			}
+			logger.assertRedacted(t)
+			for _, masked := range []string{`"customer_id":"[REDACTED]"`, `"customer_email":"[REDACTED]"`, `"customer_name":"[REDACTED]"`} {
+				if !strings.Contains(logger.output(), masked) {
+					t.Errorf("log output doesn't contain %s:\n%s", masked, logger.output())
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingInterceptorRedactsAPIKeyOfRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data":{"code":"ABC","metadata":{"customer_id":%q}}}`, testLoggedMetadata)
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	apiCallContext := coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig(testLoggedAPIKey, "2018-03-22",
+			coinbasecommerce.APIConfigOptionBaseURL(server.URL)),
+		coinbasecommerce.APICallContextOptionLogger(logger, coinbasecommerce.LoggingOptionBodies()),
+	)
+	_, _, err := charges.Create(apiCallContext, charges.CreateRequest{
+		Name:        "Order",
+		Description: "An order",
+		PricingType: coinbasecommerce.PricingTypeNone,
+		Metadata:    map[string]string{"customer_id": testLoggedMetadata},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.Contains(logger.output(), "charges.Create") {
+		t.Errorf("log output doesn't contain the operation:\n%s", logger.output())
+	}
+	logger.assertRedacted(t)
+}
+
+// recordingLogger is a Logger that records the levels, messages and
+// arguments of the entries.
+type recordingLogger struct {
+	entries []string
+}
+
+func (logger *recordingLogger) log(level, msg string, args []interface{}) {
+	logger.entries = append(logger.entries, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (logger *recordingLogger) Debug(msg string, args ...interface{}) { logger.log("debug", msg, args) }
+func (logger *recordingLogger) Info(msg string, args ...interface{})  { logger.log("info", msg, args) }
+func (logger *recordingLogger) Warn(msg string, args ...interface{})  { logger.log("warn", msg, args) }
+func (logger *recordingLogger) Error(msg string, args ...interface{}) { logger.log("error", msg, args) }
+
+// levels returns the levels of the entries, separated by commas.
+func (logger *recordingLogger) levels() string {
+	levels := make([]string, len(logger.entries))
+	for i, entry := range logger.entries {
+		levels[i] = strings.SplitN(entry, " ", 2)[0]
+	}
+	return strings.Join(levels, ",")
+}
+
+func (logger *recordingLogger) output() string {
+	return strings.Join(logger.entries, "\n")
+}
+
+// assertRedacted checks that the API key, the personal fields and the
+// metadata values weren't logged.
+func (logger *recordingLogger) assertRedacted(t *testing.T) {
+	t.Helper()
+	for _, secret := range []string{testLoggedAPIKey, testLoggedEmail, testLoggedName, testLoggedMetadata} {
+		if strings.Contains(logger.output(), secret) {
+			t.Errorf("log output contains %q:\n%s", secret, logger.output())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MetricsErrorTypeLocal is the error type of the API calls that failed
+// locally, e.g. because the API couldn't be reached.
+const MetricsErrorTypeLocal = "local"
+
+// APICallObservation contains the measurements of an API call.
+type APICallObservation struct {
+	// Operation is the name of the operation, e.g. "charges.Create".
+	Operation string
+	// Latency is how long the call took, including its retries.
+	Latency time.Duration
+	// Retries is the number of times the request was sent again.
+	Retries int
+	// Warnings is the number of warnings that was received from the API.
+	Warnings int
+	// ErrorType is the type of the error of the call, i.e. APIError.Type or
+	// MetricsErrorTypeLocal; empty if the call succeeded.
+	ErrorType string
+}
+
+// Metrics represents a collector of the metrics of API calls.
+type Metrics interface {
+	ObserveAPICall(observation APICallObservation)
+}
+
+// NewMetricsInterceptor creates an interceptor that reports the measurements
+// of every API call to the metrics collector.
+func NewMetricsInterceptor(metrics Metrics) APIInterceptor {
+	if metrics == nil {
+		panic("metrics cannot be equal to nil")
+	}
+
+	return func(ctx context.Context, call *APICall, next APIInvoker) error {
+		start := time.Now()
+		err := next(ctx, call)
+
+		observation := APICallObservation{
+			Operation: call.Operation,
+			Latency:   time.Since(start),
+			Warnings:  len(call.Warnings),
+		}
+		if call.Attempts > 1 {
+			observation.Retries = call.Attempts - 1
+		}
+		if err != nil {
+			var apiError APIError
+			if errors.As(err, &apiError) {
+				observation.ErrorType = apiError.Type
+			} else {
+				observation.ErrorType = MetricsErrorTypeLocal
+			}
+		}
+		metrics.ObserveAPICall(observation)
+
+		return err
+	}
+}
+
+// APICallContextOptionMetrics appends a metrics interceptor to the chain that
+// the API call will be passed through. See NewMetricsInterceptor.
+func APICallContextOptionMetrics(metrics Metrics) APICallContextOptionFunc {
+	return APICallContextOptionInterceptors(NewMetricsInterceptor(metrics))
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Names of the metrics that are exposed by the Registry.
+const (
+	MetricCallsTotal    = "coinbasecommerce_api_calls_total"
+	MetricErrorsTotal   = "coinbasecommerce_api_errors_total"
+	MetricRetriesTotal  = "coinbasecommerce_api_retries_total"
+	MetricWarningsTotal = "coinbasecommerce_api_warnings_total"
+	MetricCallDuration  = "coinbasecommerce_api_call_duration_seconds"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the default upper bounds, in seconds, of the buckets of
+// the latency histograms.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type operationMetrics struct {
+	calls          uint64
+	errors         map[string]uint64
+	retries        uint64
+	warnings       uint64
+	bucketCounts   []uint64
+	latencySum     float64
+	latencyBuckets []float64
+}
+
+// Registry collects the metrics of API calls per operation, and exposes them
+// in the Prometheus text exposition format when it's served over HTTP. It's
+// safe for concurrent use by multiple goroutines.
+type Registry struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	operations map[string]*operationMetrics
+}
+
+// RegistryOptionFunc represents a function that can modify the contents of
+// the Registry before it's used.
+type RegistryOptionFunc func(*Registry)
+
+// RegistryOptionBuckets sets the upper bounds, in seconds, of the buckets of
+// the latency histograms.
+func RegistryOptionBuckets(buckets ...float64) RegistryOptionFunc {
+	if len(buckets) == 0 || !sort.Float64sAreSorted(buckets) {
+		panic(`invalid histogram buckets. valid value must be non-empty and sorted`)
+	}
+	return func(registry *Registry) {
+		registry.buckets = append([]float64(nil), buckets...)
+	}
+}
+
+// NewRegistry creates a new metrics registry.
+func NewRegistry(optionFuncs ...RegistryOptionFunc) *Registry {
+	registry := Registry{
+		buckets:    DefaultBuckets,
+		operations: make(map[string]*operationMetrics),
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&registry)
+	}
+	return &registry
+}
+
+// ObserveAPICall records the measurements of an API call.
+func (registry *Registry) ObserveAPICall(observation coinbasecommerce.APICallObservation) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	metrics, ok := registry.operations[observation.Operation]
+	if !ok {
+		metrics = &operationMetrics{
+			errors:         make(map[string]uint64),
+			bucketCounts:   make([]uint64, len(registry.buckets)),
+			latencyBuckets: registry.buckets,
+		}
+		registry.operations[observation.Operation] = metrics
+	}
+
+	metrics.calls++
+	if observation.ErrorType != "" {
+		metrics.errors[observation.ErrorType]++
+	}
+	metrics.retries += uint64(observation.Retries)
+	metrics.warnings += uint64(observation.Warnings)
+
+	latency := observation.Latency.Seconds()
+	metrics.latencySum += latency
+	for i, upperBound := range metrics.latencyBuckets {
+		if latency <= upperBound {
+			metrics.bucketCounts[i]++
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	registry.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	operations := make([]string, 0, len(registry.operations))
+	for operation := range registry.operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	var b strings.Builder
+
+	writeHeader(&b, MetricCallsTotal, "counter", "Total number of Coinbase Commerce API calls.")
+	for _, operation := range operations {
+		writeSample(&b, MetricCallsTotal, labels("operation", operation),
+			formatUint(registry.operations[operation].calls))
+	}
+
+	writeHeader(&b, MetricErrorsTotal, "counter", "Total number of failed Coinbase Commerce API calls by error type.")
+	for _, operation := range operations {
+		errors := registry.operations[operation].errors
+		errorTypes := make([]string, 0, len(errors))
+		for errorType := range errors {
+			errorTypes = append(errorTypes, errorType)
+		}
+		sort.Strings(errorTypes)
+		for _, errorType := range errorTypes {
+			writeSample(&b, MetricErrorsTotal, labels("operation", operation, "type", errorType),
+				formatUint(errors[errorType]))
+		}
+	}
+
+	writeHeader(&b, MetricRetriesTotal, "counter", "Total number of retried Coinbase Commerce API requests.")
+	for _, operation := range operations {
+		writeSample(&b, MetricRetriesTotal, labels("operation", operation),
+			formatUint(registry.operations[operation].retries))
+	}
+
+	writeHeader(&b, MetricWarningsTotal, "counter", "Total number of warnings received from the Coinbase Commerce API.")
+	for _, operation := range operations {
+		writeSample(&b, MetricWarningsTotal, labels("operation", operation),
+			formatUint(registry.operations[operation].warnings))
+	}
+
+	writeHeader(&b, MetricCallDuration, "histogram", "Latency of Coinbase Commerce API calls in seconds.")
+	for _, operation := range operations {
+		metrics := registry.operations[operation]
+		for i, upperBound := range metrics.latencyBuckets {
+			writeSample(&b, MetricCallDuration+"_bucket",
+				labels("operation", operation, "le", formatFloat(upperBound)),
+				formatUint(metrics.bucketCounts[i]))
+		}
+		writeSample(&b, MetricCallDuration+"_bucket",
+			labels("operation", operation, "le", "+Inf"), formatUint(metrics.calls))
+		writeSample(&b, MetricCallDuration+"_sum",
+			labels("operation", operation), formatFloat(metrics.latencySum))
+		writeSample(&b, MetricCallDuration+"_count",
+			labels("operation", operation), formatUint(metrics.calls))
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeHeader(b *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeSample(b *strings.Builder, name, labels, value string) {
+	fmt.Fprintf(b, "%s{%s} %s\n", name, labels, value)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(namesAndValues ...string) string {
+	pairs := make([]string, 0, len(namesAndValues)/2)
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(
+			`%s="%s"`, namesAndValues[i], labelValueReplacer.Replace(namesAndValues[i+1]),
+		))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func formatUint(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
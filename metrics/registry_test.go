@@ -0,0 +1,113 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/metrics"
+)
+
+// wantExposition is the exposition of the observations of
+// TestRegistryExposition.
+const wantExposition = `# HELP coinbasecommerce_api_calls_total Total number of Coinbase Commerce API calls.
+# TYPE coinbasecommerce_api_calls_total counter
+coinbasecommerce_api_calls_total{operation="charges.Create"} 4
+coinbasecommerce_api_calls_total{operation="test \"quoted\\path\"\n"} 1
+# HELP coinbasecommerce_api_errors_total Total number of failed Coinbase Commerce API calls by error type.
+# TYPE coinbasecommerce_api_errors_total counter
+coinbasecommerce_api_errors_total{operation="charges.Create",type="invalid_request"} 1
+coinbasecommerce_api_errors_total{operation="charges.Create",type="local"} 2
+# HELP coinbasecommerce_api_retries_total Total number of retried Coinbase Commerce API requests.
+# TYPE coinbasecommerce_api_retries_total counter
+coinbasecommerce_api_retries_total{operation="charges.Create"} 3
+coinbasecommerce_api_retries_total{operation="test \"quoted\\path\"\n"} 0
+# HELP coinbasecommerce_api_warnings_total Total number of warnings received from the Coinbase Commerce API.
+# TYPE coinbasecommerce_api_warnings_total counter
+coinbasecommerce_api_warnings_total{operation="charges.Create"} 1
+coinbasecommerce_api_warnings_total{operation="test \"quoted\\path\"\n"} 0
+# HELP coinbasecommerce_api_call_duration_seconds Latency of Coinbase Commerce API calls in seconds.
+# TYPE coinbasecommerce_api_call_duration_seconds histogram
+coinbasecommerce_api_call_duration_seconds_bucket{operation="charges.Create",le="0.1"} 1
+coinbasecommerce_api_call_duration_seconds_bucket{operation="charges.Create",le="1"} 2
+coinbasecommerce_api_call_duration_seconds_bucket{operation="charges.Create",le="10"} 3
+coinbasecommerce_api_call_duration_seconds_bucket{operation="charges.Create",le="+Inf"} 4
+coinbasecommerce_api_call_duration_seconds_sum{operation="charges.Create"} 23.0625
+coinbasecommerce_api_call_duration_seconds_count{operation="charges.Create"} 4
+coinbasecommerce_api_call_duration_seconds_bucket{operation="test \"quoted\\path\"\n",le="0.1"} 0
+coinbasecommerce_api_call_duration_seconds_bucket{operation="test \"quoted\\path\"\n",le="1"} 0
+coinbasecommerce_api_call_duration_seconds_bucket{operation="test \"quoted\\path\"\n",le="10"} 1
+coinbasecommerce_api_call_duration_seconds_bucket{operation="test \"quoted\\path\"\n",le="+Inf"} 1
+coinbasecommerce_api_call_duration_seconds_sum{operation="test \"quoted\\path\"\n"} 2.5
+coinbasecommerce_api_call_duration_seconds_count{operation="test \"quoted\\path\"\n"} 1
+`
+
+func TestRegistryExposition(t *testing.T) {
+	registry := metrics.NewRegistry(metrics.RegistryOptionBuckets(0.1, 1, 10))
+	observations := []coinbasecommerce.APICallObservation{
+		{Operation: "charges.Create", Latency: 62500 * time.Microsecond},
+		// the upper bounds of the buckets are inclusive
+		{Operation: "charges.Create", Latency: time.Second, Retries: 1, ErrorType: coinbasecommerce.APIErrorTypeInvalidRequest},
+		{Operation: "charges.Create", Latency: 2 * time.Second, Warnings: 1, ErrorType: coinbasecommerce.MetricsErrorTypeLocal},
+		{Operation: "charges.Create", Latency: 20 * time.Second, Retries: 2, ErrorType: coinbasecommerce.MetricsErrorTypeLocal},
+		{Operation: "test \"quoted\\path\"\n", Latency: 2500 * time.Millisecond},
+	}
+	for _, observation := range observations {
+		registry.ObserveAPICall(observation)
+	}
+
+	var b strings.Builder
+	if _, err := registry.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != wantExposition {
+		t.Errorf("WriteTo() wrote:\n%s\nwant:\n%s", got, wantExposition)
+	}
+
+	response := httptest.NewRecorder()
+	registry.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := response.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", contentType)
+	}
+	if got := response.Body.String(); got != wantExposition {
+		t.Errorf("ServeHTTP() wrote:\n%s\nwant:\n%s", got, wantExposition)
+	}
+}
+
+func TestRegistryThroughInterceptor(t *testing.T) {
+	registry := metrics.NewRegistry()
+	interceptor := coinbasecommerce.NewMetricsInterceptor(registry)
+	calls := []struct {
+		attempts int
+		warnings coinbasecommerce.Warnings
+		err      error
+	}{
+		{1, nil, nil},
+		{3, coinbasecommerce.Warnings{"deprecated"}, coinbasecommerce.ErrAPINotFound},
+	}
+	for _, c := range calls {
+		interceptor(context.Background(), &coinbasecommerce.APICall{Operation: "charges.Get"},
+			func(_ context.Context, call *coinbasecommerce.APICall) error {
+				call.Attempts, call.Warnings = c.attempts, c.warnings
+				return c.err
+			})
+	}
+
+	var b strings.Builder
+	registry.WriteTo(&b)
+	for _, want := range []string{
+		`coinbasecommerce_api_calls_total{operation="charges.Get"} 2`,
+		`coinbasecommerce_api_errors_total{operation="charges.Get",type="not_found"} 1`,
+		`coinbasecommerce_api_retries_total{operation="charges.Get"} 2`,
+		`coinbasecommerce_api_warnings_total{operation="charges.Get"} 1`,
+		`coinbasecommerce_api_call_duration_seconds_count{operation="charges.Get"} 2`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteTo() wrote:\n%s\nwant a line %s", b.String(), want)
+		}
+	}
+}
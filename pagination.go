@@ -29,7 +29,7 @@ func (options PaginationOption) MakeQueryString() string {
 		values.Set("order", string(options.order))
 	}
 	if options.limit != 25 {
-		values.Set("limit", string(options.limit))
+		values.Set("limit", strconv.Itoa(options.limit))
 	}
 	if options.startingAfter != "" {
 		values.Set("starting_after", options.startingAfter)
@@ -0,0 +1,196 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Header keys of the rate limit that's reported by the Coinbase Commerce API.
+const (
+	APIHeaderRateLimitLimit     = "X-RateLimit-Limit"
+	APIHeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	APIHeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RateLimit contains the rate limit that was reported by the API.
+type RateLimit struct {
+	// Limit is the number of requests that are allowed in the current window.
+	Limit int
+	// Remaining is the number of requests that are left in the current window.
+	Remaining int
+	// Reset is when the current window ends; zero if it wasn't reported.
+	Reset time.Time
+	// ReportedAt is when the rate limit was received.
+	ReportedAt time.Time
+}
+
+// RateLimiter is a token bucket that limits the rate of the requests to the
+// Coinbase Commerce API. It should be shared by all the calls that use the
+// same API key, e.g. by attaching it to their API configuration. Each request
+// takes as many tokens as the weight of its operation. It's safe for
+// concurrent use by multiple goroutines.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	weights map[string]float64
+
+	mu           sync.Mutex
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	rateLimit    RateLimit
+	hasRateLimit bool
+}
+
+// RateLimiterOptionFunc represents a function that can modify the contents
+// of the RateLimiter before it's used.
+type RateLimiterOptionFunc func(*RateLimiter)
+
+// RateLimiterOptionWeight sets the number of tokens that a request of the
+// operation, e.g. "charges.List", takes. The weight of an operation is 1 by
+// default.
+func RateLimiterOptionWeight(operation string, weight float64) RateLimiterOptionFunc {
+	if weight <= 0 {
+		panic(`invalid rate limiter weight. valid values: weight > 0`)
+	}
+	return func(limiter *RateLimiter) {
+		limiter.weights[operation] = weight
+	}
+}
+
+// NewRateLimiter creates a new rate limiter which allows requestsPerSecond
+// requests per second on average, and bursts of up to burst requests.
+func NewRateLimiter(
+	requestsPerSecond float64,
+	burst int,
+	optionFuncs ...RateLimiterOptionFunc,
+) *RateLimiter {
+	if requestsPerSecond <= 0 || burst < 1 {
+		panic(`invalid rate limit. valid values: requestsPerSecond > 0, burst >= 1`)
+	}
+
+	limiter := RateLimiter{
+		rate:    requestsPerSecond,
+		burst:   float64(burst),
+		weights: make(map[string]float64),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&limiter)
+	}
+	return &limiter
+}
+
+// Wait blocks until a request of the operation is allowed, or until the
+// context is done, in which case the error of the context is returned. If
+// the API reported that the rate limit has been exhausted, Wait also blocks
+// until the reported limit resets.
+func (limiter *RateLimiter) Wait(ctx context.Context, operation string) error {
+	weight, ok := limiter.weights[operation]
+	if !ok {
+		weight = 1
+	}
+	if weight > limiter.burst {
+		weight = limiter.burst
+	}
+
+	limiter.mu.Lock()
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+
+	// reserve the tokens, and then wait until they're refilled
+	limiter.tokens -= weight
+	var wait time.Duration
+	if limiter.tokens < 0 {
+		wait = time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	}
+	if blocked := limiter.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	limiter.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		limiter.mu.Lock()
+		limiter.tokens += weight
+		limiter.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Observe records the rate limit that's reported in the headers of the
+// response, if there's any. If the rate limit has been exhausted, or the
+// response's status code is 429 and it has a `Retry-After` header, the
+// requests are held back until the limit resets.
+func (limiter *RateLimiter) Observe(response *http.Response) {
+	now := time.Now()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if rateLimit, ok := parseRateLimit(response.Header, now); ok {
+		limiter.rateLimit = rateLimit
+		limiter.hasRateLimit = true
+		if rateLimit.Remaining == 0 && rateLimit.Reset.After(limiter.blockedUntil) {
+			limiter.blockedUntil = rateLimit.Reset
+		}
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			if until := now.Add(retryAfter); until.After(limiter.blockedUntil) {
+				limiter.blockedUntil = until
+			}
+		}
+	}
+}
+
+// RateLimit returns the last rate limit that was reported by the API, if any.
+func (limiter *RateLimiter) RateLimit() (RateLimit, bool) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.rateLimit, limiter.hasRateLimit
+}
+
+func parseRateLimit(header http.Header, now time.Time) (RateLimit, bool) {
+	limit, err := strconv.Atoi(header.Get(APIHeaderRateLimitLimit))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(header.Get(APIHeaderRateLimitRemaining))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	rateLimit := RateLimit{
+		Limit:      limit,
+		Remaining:  remaining,
+		ReportedAt: now,
+	}
+	if reset, err := strconv.ParseInt(header.Get(APIHeaderRateLimitReset), 10, 64); err == nil {
+		// the reset is either a unix timestamp or a number of seconds
+		if reset > 1000000000 {
+			rateLimit.Reset = time.Unix(reset, 0)
+		} else {
+			rateLimit.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return rateLimit, true
+}
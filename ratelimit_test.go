@@ -0,0 +1,182 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		limiter  *RateLimiter
+		waits    []string
+		minDelay time.Duration
+	}{
+		{
+			name:     "within burst",
+			limiter:  NewRateLimiter(10, 3),
+			waits:    []string{"charges.Get", "charges.Get", "charges.Get"},
+			minDelay: 0,
+		},
+		{
+			name:     "beyond burst",
+			limiter:  NewRateLimiter(20, 1),
+			waits:    []string{"charges.Get", "charges.Get"},
+			minDelay: 40 * time.Millisecond,
+		},
+		{
+			name:     "weighted operation",
+			limiter:  NewRateLimiter(20, 2, RateLimiterOptionWeight("charges.List", 2)),
+			waits:    []string{"charges.List", "charges.Get"},
+			minDelay: 40 * time.Millisecond,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			for _, operation := range test.waits {
+				if err := test.limiter.Wait(context.Background(), operation); err != nil {
+					t.Fatalf("Wait(%q) = %v", operation, err)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < test.minDelay {
+				t.Errorf("waited %s, want at least %s", elapsed, test.minDelay)
+			}
+			if test.minDelay == 0 && elapsed > 20*time.Millisecond {
+				t.Errorf("waited %s, want no delay", elapsed)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCanceledRefundsTokens(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	if err := limiter.Wait(context.Background(), "charges.Get"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "charges.Get"); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	limiter.mu.Lock()
+	tokens := limiter.tokens
+	limiter.mu.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("tokens = %f after the canceled wait, want the reservation to be refunded", tokens)
+	}
+}
+
+func TestRateLimiterObserveBlocks(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		blocked    bool
+	}{
+		{
+			name:       "exhausted rate limit",
+			statusCode: http.StatusOK,
+			header:     rateLimitHeader("100", "0", "60"),
+			blocked:    true,
+		},
+		{
+			name:       "retry after",
+			statusCode: http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"60"}},
+			blocked:    true,
+		},
+		{
+			name:       "retry after without 429",
+			statusCode: http.StatusServiceUnavailable,
+			header:     http.Header{"Retry-After": {"60"}},
+			blocked:    false,
+		},
+		{
+			name:       "remaining rate limit",
+			statusCode: http.StatusOK,
+			header:     rateLimitHeader("100", "5", "60"),
+			blocked:    false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := NewRateLimiter(100, 10)
+			limiter.Observe(&http.Response{StatusCode: test.statusCode, Header: test.header})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			err := limiter.Wait(ctx, "charges.Get")
+			if blocked := err != nil; blocked != test.blocked {
+				t.Errorf("Wait() = %v, want blocked = %v", err, test.blocked)
+			}
+		})
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   RateLimit
+		ok     bool
+	}{
+		{
+			name:   "relative reset",
+			header: rateLimitHeader("100", "42", "30"),
+			want:   RateLimit{Limit: 100, Remaining: 42, Reset: now.Add(30 * time.Second), ReportedAt: now},
+			ok:     true,
+		},
+		{
+			name:   "unix reset",
+			header: rateLimitHeader("100", "0", "1600000060"),
+			want:   RateLimit{Limit: 100, Remaining: 0, Reset: time.Unix(1600000060, 0), ReportedAt: now},
+			ok:     true,
+		},
+		{
+			name:   "no reset",
+			header: rateLimitHeader("100", "1", ""),
+			want:   RateLimit{Limit: 100, Remaining: 1, ReportedAt: now},
+			ok:     true,
+		},
+		{
+			name:   "missing remaining",
+			header: rateLimitHeader("100", "", ""),
+			ok:     false,
+		},
+		{
+			name:   "no headers",
+			header: http.Header{},
+			ok:     false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := parseRateLimit(test.header, now)
+			if ok != test.ok || !got.Reset.Equal(test.want.Reset) || got.Limit != test.want.Limit ||
+				got.Remaining != test.want.Remaining || (ok && !got.ReportedAt.Equal(now)) {
+				t.Errorf("parseRateLimit() = %+v, %v, want %+v, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func rateLimitHeader(limit, remaining, reset string) http.Header {
+	header := make(http.Header)
+	for key, value := range map[string]string{
+		APIHeaderRateLimitLimit:     limit,
+		APIHeaderRateLimitRemaining: remaining,
+		APIHeaderRateLimitReset:     reset,
+	} {
+		if value != "" {
+			header.Set(key, value)
+		}
+	}
+	return header
+}
@@ -0,0 +1,51 @@
+package coinbasecommerce
+
+import (
+	"net/http"
+	"time"
+)
+
+// ResponseMeta contains the details of the HTTP exchange of an API call.
+type ResponseMeta struct {
+	// Operation is the name of the operation, e.g. "checkouts.Get".
+	Operation string
+	// StatusCode is the HTTP status code of the response; equal to 0 if no
+	// response was received.
+	StatusCode int
+	// Header contains the headers of the response.
+	Header http.Header
+	// RequestID is the ID that the API gave to the request, if there's any.
+	RequestID string
+	// RateLimit is the rate limit that was reported in the headers of the
+	// response; equal to nil if it wasn't reported.
+	RateLimit *RateLimit
+	// RawBody is the raw JSON body of the response.
+	RawBody []byte
+	// StartTime is when the call was started.
+	StartTime time.Time
+	// Duration is how long the call took, including its retries.
+	Duration time.Duration
+	// Attempts is the number of times the request was sent.
+	Attempts int
+}
+
+// NewResponseMeta creates the response metadata of an API call that has
+// finished.
+func NewResponseMeta(call *APICall, startTime time.Time, duration time.Duration) ResponseMeta {
+	meta := ResponseMeta{
+		Operation:  call.Operation,
+		StatusCode: call.StatusCode,
+		Header:     call.ResponseHeader,
+		RawBody:    call.ResponseBody,
+		StartTime:  startTime,
+		Duration:   duration,
+		Attempts:   call.Attempts,
+	}
+	if call.ResponseHeader != nil {
+		meta.RequestID = call.ResponseHeader.Get(APIHeaderRequestID)
+		if rateLimit, ok := parseRateLimit(call.ResponseHeader, startTime.Add(duration)); ok {
+			meta.RateLimit = &rateLimit
+		}
+	}
+	return meta
+}
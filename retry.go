@@ -0,0 +1,149 @@
+package coinbasecommerce
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how requests to the Coinbase Commerce API that have
+// failed transiently, e.g. because of a connection reset or a 5xx or 429
+// response, are retried. Only safe requests and requests that are explicitly
+// idempotent are retried.
+type RetryPolicy struct {
+	maxAttempts     int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	jitter          float64
+	honorRetryAfter bool
+}
+
+// MaxAttempts returns the maximum number of times a request is attempted.
+func (policy *RetryPolicy) MaxAttempts() int {
+	return policy.maxAttempts
+}
+
+// IsRetryableStatus returns true if a response with the status code should
+// be retried.
+func (policy *RetryPolicy) IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Backoff returns how long to wait before attempting a request again after
+// its nth attempt has failed. The response of the failed attempt may be equal
+// to nil; if it's not and it has a `Retry-After` header, the header is used
+// instead of the exponential backoff unless the policy ignores it. The
+// backoff never exceeds the maximum backoff of the policy, whatever the
+// header says.
+func (policy *RetryPolicy) Backoff(attempt int, response *http.Response) time.Duration {
+	if policy.honorRetryAfter && response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			if retryAfter > policy.maxBackoff {
+				retryAfter = policy.maxBackoff
+			}
+			return retryAfter
+		}
+	}
+
+	backoff := policy.initialBackoff
+	for i := 1; i < attempt && backoff < policy.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.maxBackoff {
+		backoff = policy.maxBackoff
+	}
+	if policy.jitter > 0 {
+		delta := policy.jitter * float64(backoff)
+		backoff = time.Duration(float64(backoff) - delta + rand.Float64()*2*delta)
+	}
+	return backoff
+}
+
+func parseRetryAfter(retryAfter string) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// RetryPolicyOptionFunc represents a function that can modify the contents
+// of the RetryPolicy.
+type RetryPolicyOptionFunc func(*RetryPolicy)
+
+// RetryPolicyOptionMaxAttempts sets the maximum number of times a request is
+// attempted, including the first attempt.
+func RetryPolicyOptionMaxAttempts(maxAttempts int) RetryPolicyOptionFunc {
+	if maxAttempts < 1 {
+		panic(`invalid retry max attempts. valid values: maxAttempts >= 1`)
+	}
+	return func(policy *RetryPolicy) {
+		policy.maxAttempts = maxAttempts
+	}
+}
+
+// RetryPolicyOptionBackoff sets the backoff before the first retry, which is
+// doubled after every failed attempt until it reaches the maximum backoff.
+func RetryPolicyOptionBackoff(initialBackoff, maxBackoff time.Duration) RetryPolicyOptionFunc {
+	if initialBackoff < 0 || maxBackoff < initialBackoff {
+		panic(`invalid retry backoff. valid values: 0 <= initialBackoff <= maxBackoff`)
+	}
+	return func(policy *RetryPolicy) {
+		policy.initialBackoff = initialBackoff
+		policy.maxBackoff = maxBackoff
+	}
+}
+
+// RetryPolicyOptionJitter sets the fraction of the backoff that's randomly
+// added to or subtracted from it.
+func RetryPolicyOptionJitter(jitter float64) RetryPolicyOptionFunc {
+	if jitter < 0 || jitter > 1 {
+		panic(`invalid retry jitter. valid values: 0 <= jitter <= 1`)
+	}
+	return func(policy *RetryPolicy) {
+		policy.jitter = jitter
+	}
+}
+
+// RetryPolicyOptionIgnoreRetryAfter makes the retry policy ignore the
+// `Retry-After` header of the responses.
+func RetryPolicyOptionIgnoreRetryAfter() RetryPolicyOptionFunc {
+	return func(policy *RetryPolicy) {
+		policy.honorRetryAfter = false
+	}
+}
+
+// NewRetryPolicy creates a new retry policy. By default, a request is
+// attempted at most 3 times with a backoff of 500ms, 1s, ... up to 10s, with
+// 20% jitter, and the `Retry-After` header is honored.
+func NewRetryPolicy(optionFuncs ...RetryPolicyOptionFunc) *RetryPolicy {
+	policy := RetryPolicy{
+		maxAttempts:     3,
+		initialBackoff:  500 * time.Millisecond,
+		maxBackoff:      10 * time.Second,
+		jitter:          0.2,
+		honorRetryAfter: true,
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&policy)
+	}
+	return &policy
+}
@@ -0,0 +1,126 @@
+package coinbasecommerce
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := NewRetryPolicy(
+		RetryPolicyOptionBackoff(100*time.Millisecond, time.Second),
+		RetryPolicyOptionJitter(0),
+	)
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{100, time.Second},
+	}
+	for _, test := range tests {
+		if got := policy.Backoff(test.attempt, nil); got != test.want {
+			t.Errorf("Backoff(%d, nil) = %s, want %s", test.attempt, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := NewRetryPolicy(
+		RetryPolicyOptionBackoff(time.Second, time.Second),
+		RetryPolicyOptionJitter(0.2),
+	)
+	for i := 0; i < 1000; i++ {
+		if got := policy.Backoff(1, nil); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("Backoff(1, nil) = %s, want between 800ms and 1.2s", got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		ignore     bool
+		want       time.Duration
+	}{
+		{"no header", "", false, 100 * time.Millisecond},
+		{"seconds", "2", false, 2 * time.Second},
+		{"zero seconds", "0", false, 0},
+		{"longer than the maximum backoff", "3600", false, 10 * time.Second},
+		{"date longer than the maximum backoff", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), false, 10 * time.Second},
+		{"date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), false, 0},
+		{"invalid", "soon", false, 100 * time.Millisecond},
+		{"negative", "-1", false, 100 * time.Millisecond},
+		{"ignored", "2", true, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			optionFuncs := []RetryPolicyOptionFunc{
+				RetryPolicyOptionBackoff(100*time.Millisecond, 10*time.Second),
+				RetryPolicyOptionJitter(0),
+			}
+			if test.ignore {
+				optionFuncs = append(optionFuncs, RetryPolicyOptionIgnoreRetryAfter())
+			}
+			response := &http.Response{Header: make(http.Header)}
+			if test.retryAfter != "" {
+				response.Header.Set("Retry-After", test.retryAfter)
+			}
+
+			if got := NewRetryPolicy(optionFuncs...).Backoff(1, response); got != test.want {
+				t.Errorf("Backoff(1, Retry-After: %q) = %s, want %s", test.retryAfter, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		want       time.Duration
+		wantOK     bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"-5", 0, false},
+		{"1.5", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true},
+		{"tomorrow", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.retryAfter)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", test.retryAfter, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestRetryPolicyIsRetryableStatus(t *testing.T) {
+	policy := NewRetryPolicy()
+	tests := []struct {
+		statusCode int
+		want       bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusNotImplemented, false},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusGatewayTimeout, true},
+	}
+	for _, test := range tests {
+		if got := policy.IsRetryableStatus(test.statusCode); got != test.want {
+			t.Errorf("IsRetryableStatus(%d) = %v, want %v", test.statusCode, got, test.want)
+		}
+	}
+}
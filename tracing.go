@@ -0,0 +1,48 @@
+package coinbasecommerce
+
+import (
+	"context"
+	"net/http"
+)
+
+// Attribute keys of the spans that are started around API calls.
+const (
+	TraceAttributeOperation  = "coinbasecommerce.operation"
+	TraceAttributeAttempt    = "coinbasecommerce.attempt"
+	TraceAttributeAttempts   = "coinbasecommerce.attempts"
+	TraceAttributeWarnings   = "coinbasecommerce.warnings"
+	TraceAttributeErrorType  = "coinbasecommerce.error_type"
+	TraceAttributeMethod     = "http.method"
+	TraceAttributeURL        = "http.url"
+	TraceAttributeStatusCode = "http.status_code"
+)
+
+// Tracer represents a function that starts spans. A span is started around
+// every API call, named after its operation, e.g. "charges.Create", and
+// around every attempt to send its request, named after its operation with
+// an " attempt" suffix. The spans of the attempts are children of the span of
+// the call, which is a child of the span in the context of the call, if any.
+//
+// The shape of Tracer and Span follows OpenTelemetry's, so an adapter of an
+// OpenTelemetry tracer only needs to convert the attributes, e.g.
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, coinbasecommerce.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents an operation that's being traced.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// TracePropagator can optionally be implemented by a Tracer to propagate the
+// trace context of a span, e.g. as a W3C `traceparent` header, to the API.
+type TracePropagator interface {
+	Inject(ctx context.Context, header http.Header)
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// RecordedSpan contains everything that was recorded on a span.
+type RecordedSpan struct {
+	// ID identifies the span within its recorder, starting at 1.
+	ID int
+	// ParentID is the ID of the parent of the span; 0 if it has none.
+	ParentID   int
+	Name       string
+	Attributes map[string]interface{}
+	Errors     []error
+	StartTime  time.Time
+	EndTime    time.Time
+	Ended      bool
+}
+
+// Recorder is a tracer that keeps the spans that it started in memory, e.g.
+// to assert them in tests. It's safe for concurrent use by multiple
+// goroutines.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder creates a new span recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type spanContextKey struct{}
+
+type recorderSpan struct {
+	recorder *Recorder
+	span     *RecordedSpan
+}
+
+// Start starts a span that's a child of the span in the context, if it was
+// started by the same recorder.
+func (recorder *Recorder) Start(
+	ctx context.Context,
+	name string,
+) (context.Context, coinbasecommerce.Span) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	span := &RecordedSpan{
+		ID:         len(recorder.spans) + 1,
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		StartTime:  time.Now(),
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(recorderSpan); ok && parent.recorder == recorder {
+		span.ParentID = parent.span.ID
+	}
+	recorder.spans = append(recorder.spans, span)
+
+	s := recorderSpan{recorder: recorder, span: span}
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// Spans returns a copy of the spans that were started, in the order they were
+// started.
+func (recorder *Recorder) Spans() []RecordedSpan {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	spans := make([]RecordedSpan, len(recorder.spans))
+	for i, span := range recorder.spans {
+		spans[i] = *span
+		spans[i].Attributes = make(map[string]interface{}, len(span.Attributes))
+		for key, value := range span.Attributes {
+			spans[i].Attributes[key] = value
+		}
+		spans[i].Errors = append([]error(nil), span.Errors...)
+	}
+	return spans
+}
+
+// Reset forgets the spans that were started.
+func (recorder *Recorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.spans = nil
+}
+
+func (s recorderSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+func (s recorderSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s recorderSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	if !s.span.Ended {
+		s.span.Ended = true
+		s.span.EndTime = time.Now()
+	}
+}
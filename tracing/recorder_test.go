@@ -0,0 +1,213 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/charges"
+	"github.com/bmdelacruz/coinbasecommerce/tracing"
+)
+
+func TestRecorderSpansOfRetriedCall(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"type": "service_unavailable", "message": "Try again"}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"code": "ABC"}}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	ctx, parent := recorder.Start(context.Background(), "checkout")
+	_, _, err := charges.Get(newTracedAPICallContext(ctx, recorder, server.URL), "ABC")
+	parent.End()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	spans := recorder.Spans()
+	want := []struct {
+		name           string
+		parentID       int
+		wantAttributes map[string]interface{}
+	}{
+		{"checkout", 0, map[string]interface{}{}},
+		{"charges.Get", 1, map[string]interface{}{
+			coinbasecommerce.TraceAttributeOperation:  "charges.Get",
+			coinbasecommerce.TraceAttributeMethod:     http.MethodGet,
+			coinbasecommerce.TraceAttributeURL:        server.URL + "/charges/ABC",
+			coinbasecommerce.TraceAttributeAttempts:   2,
+			coinbasecommerce.TraceAttributeWarnings:   0,
+			coinbasecommerce.TraceAttributeStatusCode: http.StatusOK,
+		}},
+		{"charges.Get attempt", 2, map[string]interface{}{
+			coinbasecommerce.TraceAttributeAttempt:    1,
+			coinbasecommerce.TraceAttributeStatusCode: http.StatusServiceUnavailable,
+		}},
+		{"charges.Get attempt", 2, map[string]interface{}{
+			coinbasecommerce.TraceAttributeAttempt:    2,
+			coinbasecommerce.TraceAttributeStatusCode: http.StatusOK,
+		}},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %+v, want %d spans", spans, len(want))
+	}
+	for i, span := range spans {
+		if span.Name != want[i].name || span.ParentID != want[i].parentID {
+			t.Errorf("span %d = %s with parent %d, want %s with parent %d",
+				span.ID, span.Name, span.ParentID, want[i].name, want[i].parentID)
+		}
+		assertAttributes(t, span, want[i].wantAttributes)
+		if !span.Ended {
+			t.Errorf("span %d (%s) didn't end", span.ID, span.Name)
+		}
+		if len(span.Errors) != 0 {
+			t.Errorf("span %d (%s) errors = %v, want none", span.ID, span.Name, span.Errors)
+		}
+	}
+}
+
+func TestRecorderSpansOfFailedCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"type": "not_found", "message": "Not found"}}`))
+	}))
+	defer server.Close()
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+
+	tests := []struct {
+		name           string
+		baseURL        string
+		wantAttempts   int
+		wantAttributes map[string]interface{}
+		wantAttemptErr bool
+	}{
+		{
+			name:         "rejected by the API",
+			baseURL:      server.URL,
+			wantAttempts: 1,
+			wantAttributes: map[string]interface{}{
+				coinbasecommerce.TraceAttributeAttempts:   1,
+				coinbasecommerce.TraceAttributeStatusCode: http.StatusNotFound,
+				coinbasecommerce.TraceAttributeErrorType:  coinbasecommerce.APIErrorTypeNotFound,
+			},
+		},
+		{
+			name:         "failed locally",
+			baseURL:      closedServer.URL,
+			wantAttempts: 3,
+			wantAttributes: map[string]interface{}{
+				coinbasecommerce.TraceAttributeAttempts:  3,
+				coinbasecommerce.TraceAttributeErrorType: coinbasecommerce.MetricsErrorTypeLocal,
+			},
+			wantAttemptErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracing.NewRecorder()
+			_, _, err := charges.Get(newTracedAPICallContext(context.Background(), recorder, tt.baseURL), "ABC")
+			if err == nil {
+				t.Fatal("Get() error = nil, want an error")
+			}
+
+			spans := recorder.Spans()
+			if len(spans) != 1+tt.wantAttempts {
+				t.Fatalf("spans = %+v, want the span of the call and of its %d attempts", spans, tt.wantAttempts)
+			}
+			call := spans[0]
+			assertAttributes(t, call, tt.wantAttributes)
+			if len(call.Errors) != 1 || !errors.Is(call.Errors[0], err) {
+				t.Errorf("call span errors = %v, want [%v]", call.Errors, err)
+			}
+			if _, ok := tt.wantAttributes[coinbasecommerce.TraceAttributeStatusCode]; !ok {
+				if statusCode, ok := call.Attributes[coinbasecommerce.TraceAttributeStatusCode]; ok {
+					t.Errorf("call span status code = %v, want none", statusCode)
+				}
+			}
+			if !call.Ended {
+				t.Error("call span didn't end")
+			}
+			for i, attempt := range spans[1:] {
+				if attempt.ParentID != call.ID {
+					t.Errorf("attempt span %d parent = %d, want %d", i+1, attempt.ParentID, call.ID)
+				}
+				assertAttributes(t, attempt, map[string]interface{}{coinbasecommerce.TraceAttributeAttempt: i + 1})
+				if got := len(attempt.Errors) != 0; got != tt.wantAttemptErr {
+					t.Errorf("attempt span %d errors = %v, want errors %v", i+1, attempt.Errors, tt.wantAttemptErr)
+				}
+				if !attempt.Ended {
+					t.Errorf("attempt span %d didn't end", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestRecorderParenting(t *testing.T) {
+	recorder, other := tracing.NewRecorder(), tracing.NewRecorder()
+	ctx, root := recorder.Start(context.Background(), "root")
+	childCtx, child := recorder.Start(ctx, "child")
+	_, grandchild := recorder.Start(childCtx, "grandchild")
+	otherCtx, _ := other.Start(context.Background(), "other")
+	_, foreign := recorder.Start(otherCtx, "foreign")
+	for _, span := range []coinbasecommerce.Span{grandchild, child, root, foreign, root} {
+		span.End()
+	}
+
+	wantParentIDs := []int{0, 1, 2, 0}
+	spans := recorder.Spans()
+	if len(spans) != len(wantParentIDs) {
+		t.Fatalf("spans = %+v, want %d spans", spans, len(wantParentIDs))
+	}
+	for i, span := range spans {
+		if span.ID != i+1 || span.ParentID != wantParentIDs[i] {
+			t.Errorf("span %s = ID %d with parent %d, want ID %d with parent %d",
+				span.Name, span.ID, span.ParentID, i+1, wantParentIDs[i])
+		}
+		if !span.Ended || span.EndTime.Before(span.StartTime) {
+			t.Errorf("span %s ended = %v at %s, want it to end after it started", span.Name, span.Ended, span.EndTime)
+		}
+	}
+
+	recorder.Reset()
+	if spans := recorder.Spans(); len(spans) != 0 {
+		t.Errorf("Spans() after Reset() = %+v, want none", spans)
+	}
+}
+
+// newTracedAPICallContext creates an API call context for the server at the
+// base URL that traces the calls with the recorder, and whose requests are
+// attempted at most 3 times without backoff.
+func newTracedAPICallContext(ctx context.Context, recorder *tracing.Recorder, baseURL string) coinbasecommerce.APICallContext {
+	return coinbasecommerce.NewAPICallContext(
+		coinbasecommerce.NewAPIConfig("key", "2018-03-22", coinbasecommerce.APIConfigOptionBaseURL(baseURL)),
+		coinbasecommerce.APICallContextOptionContext(ctx),
+		coinbasecommerce.APICallContextOptionTracer(recorder),
+		coinbasecommerce.APICallContextOptionRetryPolicy(coinbasecommerce.NewRetryPolicy(
+			coinbasecommerce.RetryPolicyOptionMaxAttempts(3),
+			coinbasecommerce.RetryPolicyOptionBackoff(0, 0),
+		)),
+	)
+}
+
+// assertAttributes checks that the span has the attributes, among others.
+func assertAttributes(t *testing.T, span tracing.RecordedSpan, attributes map[string]interface{}) {
+	t.Helper()
+	for key, want := range attributes {
+		if got, ok := span.Attributes[key]; !ok || got != want {
+			t.Errorf("span %d (%s) attribute %s = %v, want %v", span.ID, span.Name, key, got, want)
+		}
+	}
+}
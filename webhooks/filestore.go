@@ -0,0 +1,199 @@
+package webhooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// minCompactionSize is the number of records that the file of a
+// FileEventStore must have before it's compacted.
+const minCompactionSize = 1024
+
+// FileEventStore is an EventStore that records the IDs of the processed
+// events in a file, so that they're remembered when the process restarts.
+// The claims of the events that are being processed are only kept in
+// memory, so the file must not be shared by multiple processes. It's safe for
+// concurrent use by multiple goroutines.
+//
+// The file has a JSON record per line. It's compacted when it opens, and
+// when most of its records have expired.
+type FileEventStore struct {
+	path   string
+	memory *MemoryEventStore
+
+	mu      sync.Mutex
+	file    *os.File
+	records int
+}
+
+type eventRecord struct {
+	ID          string    `json:"id"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// NewFileEventStore opens the event store in the file at the path, which is
+// created if it doesn't exist. A processed event is forgotten once the TTL
+// has passed since it was completed.
+func NewFileEventStore(path string, ttl time.Duration, optionFuncs ...EventStoreOptionFunc) (*FileEventStore, error) {
+	store := FileEventStore{
+		path:   path,
+		memory: NewMemoryEventStore(ttl, optionFuncs...),
+	}
+
+	records, err := readEventRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ProcessedAt.Before(records[j].ProcessedAt)
+	})
+	for _, record := range records {
+		store.memory.add(record.ID, record.ProcessedAt)
+	}
+	store.memory.prune()
+
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// readEventRecords reads the records of the file at the path. A partially
+// written last record, which is left when the process dies while writing
+// it, is ignored.
+func readEventRecords(path string) ([]eventRecord, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []eventRecord
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var record eventRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("invalid event record on line %d of %s: %w", i+1, path, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Claim claims the event with the ID for processing.
+func (store *FileEventStore) Claim(ctx context.Context, eventID string) error {
+	return store.memory.Claim(ctx, eventID)
+}
+
+// Complete records that the claimed event with the ID was processed. The
+// record is synced to the file before it returns.
+func (store *FileEventStore) Complete(ctx context.Context, eventID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.file == nil {
+		return os.ErrClosed
+	}
+	line, err := json.Marshal(eventRecord{ID: eventID, ProcessedAt: store.memory.clock.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := store.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := store.file.Sync(); err != nil {
+		return err
+	}
+	store.records++
+
+	if err := store.memory.Complete(ctx, eventID); err != nil {
+		return err
+	}
+	if store.records >= minCompactionSize && store.records > 2*store.memory.size() {
+		// The record is already synced, so a failed compaction is retried
+		// on the next completion instead.
+		store.compact()
+	}
+	return nil
+}
+
+// Release gives up the claim of the event with the ID.
+func (store *FileEventStore) Release(ctx context.Context, eventID string) error {
+	return store.memory.Release(ctx, eventID)
+}
+
+// Close closes the file of the store.
+func (store *FileEventStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
+
+// compact rewrites the file with the records of the processed events that
+// haven't expired, and then appends to the rewritten file. The file is
+// replaced with the rewritten one while it's open, so the store never loses
+// its handle; if the replacement fails, it keeps appending to the old file.
+// The caller must hold the lock of the store, if it's in use.
+func (store *FileEventStore) compact() error {
+	records := store.memory.records()
+
+	temp, err := ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Chmod(0644); err != nil {
+		return fail(err)
+	}
+
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fail(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := temp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(temp.Name(), store.path); err != nil {
+		return fail(err)
+	}
+
+	// the offset of the rewritten file is at its end, so it's appended to
+	if store.file != nil {
+		store.file.Close()
+	}
+	store.file = temp
+	store.records = len(records)
+	return nil
+}
@@ -0,0 +1,326 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// DefaultMaxBodySize is the default maximum size of the body of a webhook
+// request.
+const DefaultMaxBodySize = 1 << 20
+
+// ErrBodyTooLarge is returned when the body of a webhook request is larger
+// than the maximum size.
+var ErrBodyTooLarge = errors.New("webhook request body too large")
+
+var errMethodNotAllowed = errors.New("method not allowed")
+
+// releaseTimeout is how long the event store has to release an event whose
+// handlers failed.
+const releaseTimeout = 5 * time.Second
+
+// EventHandlerFunc handles an event. If it returns an error, the webhook
+// request fails so that the event is delivered again later.
+type EventHandlerFunc func(ctx context.Context, event coinbasecommerce.Event) error
+
+// ChargeHandlerFunc handles an event about a charge.
+type ChargeHandlerFunc func(ctx context.Context, event coinbasecommerce.Event, charge coinbasecommerce.Charge) error
+
+// InvoiceHandlerFunc handles an event about an invoice.
+type InvoiceHandlerFunc func(ctx context.Context, event coinbasecommerce.Event, invoice coinbasecommerce.Invoice) error
+
+// RejectionFunc is called when a webhook request is rejected, with the
+// reason and the error of the rejection.
+type RejectionFunc func(r *http.Request, reason RejectionReason, err error)
+
+// Handler is an http.Handler that receives the webhook requests. It reads
+// the body of a request, verifies its signature, decodes its event and then
+// passes the event to the handlers of its type. It responds with:
+//
+//	200 if the event was handled, if there's no handler for its type, or if
+//	    it was already processed
+//	400 if the signature or the payload is invalid, or if the event was
+//	    created outside the tolerance of the verifier
+//	405 if the method isn't POST
+//	409 if the event is being processed by another delivery of it
+//	413 if the body is too large
+//	500 if a handler failed, so that the event is delivered again later
+//
+// The handlers must be registered before the Handler starts serving.
+type Handler struct {
+	verifier    *Verifier
+	maxBodySize int64
+	logger      coinbasecommerce.Logger
+	store       EventStore
+	onRejection RejectionFunc
+
+	handlers        map[coinbasecommerce.EventType][]EventHandlerFunc
+	defaultHandlers []EventHandlerFunc
+}
+
+// HandlerOptionFunc represents a function that can modify the contents of
+// the Handler before it's used.
+type HandlerOptionFunc func(*Handler)
+
+// HandlerOptionMaxBodySize sets the maximum size of the body of a webhook
+// request, which is DefaultMaxBodySize by default.
+func HandlerOptionMaxBodySize(maxBodySize int64) HandlerOptionFunc {
+	if maxBodySize < 1 {
+		panic(`invalid max body size. valid values: maxBodySize >= 1`)
+	}
+	return func(handler *Handler) {
+		handler.maxBodySize = maxBodySize
+	}
+}
+
+// HandlerOptionLogger sets the logger that the rejected requests are logged
+// to at warn level, and the failed handlers at error level.
+func HandlerOptionLogger(logger coinbasecommerce.Logger) HandlerOptionFunc {
+	return func(handler *Handler) {
+		handler.logger = logger
+	}
+}
+
+// HandlerOptionEventStore sets the store that the handler records the
+// processed events in, so that an event that is delivered more than once is
+// only processed once. If a handler of an event fails, the event is released
+// so that it's processed again when it's delivered again; it's also released
+// if it can't be completed, so it may then be processed twice. There's no
+// event store by default.
+func HandlerOptionEventStore(store EventStore) HandlerOptionFunc {
+	return func(handler *Handler) {
+		handler.store = store
+	}
+}
+
+// HandlerOptionOnRejection sets the function that is called when a webhook
+// request is rejected, e.g. to count the rejections by their reasons. The
+// deliveries of the events that were already processed are counted as
+// rejections too, with RejectionReasonDuplicateEvent, even though they're
+// acknowledged.
+func HandlerOptionOnRejection(onRejection RejectionFunc) HandlerOptionFunc {
+	return func(handler *Handler) {
+		handler.onRejection = onRejection
+	}
+}
+
+// NewHandler creates a new handler that verifies the signatures of the
+// requests using the verifier.
+func NewHandler(verifier *Verifier, optionFuncs ...HandlerOptionFunc) *Handler {
+	if verifier == nil {
+		panic("verifier cannot be equal to nil")
+	}
+
+	handler := Handler{
+		verifier:    verifier,
+		maxBodySize: DefaultMaxBodySize,
+		logger:      nil,
+		store:       nil,
+		onRejection: nil,
+		handlers:    make(map[coinbasecommerce.EventType][]EventHandlerFunc),
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&handler)
+	}
+	return &handler
+}
+
+// On registers a handler of the events of the type. The handlers of a type
+// are called in the order that they were registered, until one of them
+// fails.
+func (handler *Handler) On(eventType coinbasecommerce.EventType, f EventHandlerFunc) {
+	handler.handlers[eventType] = append(handler.handlers[eventType], f)
+}
+
+// OnDefault registers a handler of the events whose types have no handlers.
+func (handler *Handler) OnDefault(f EventHandlerFunc) {
+	handler.defaultHandlers = append(handler.defaultHandlers, f)
+}
+
+// OnCharge registers a handler of the charge events of the type.
+func (handler *Handler) OnCharge(eventType coinbasecommerce.EventType, f ChargeHandlerFunc) {
+	handler.On(eventType, func(ctx context.Context, event coinbasecommerce.Event) error {
+		charge, ok := event.Charge()
+		if !ok {
+			return fmt.Errorf("%s event has no charge", event.Type)
+		}
+		return f(ctx, event, charge)
+	})
+}
+
+// OnInvoice registers a handler of the invoice events of the type.
+func (handler *Handler) OnInvoice(eventType coinbasecommerce.EventType, f InvoiceHandlerFunc) {
+	handler.On(eventType, func(ctx context.Context, event coinbasecommerce.Event) error {
+		invoice, ok := event.Invoice()
+		if !ok {
+			return fmt.Errorf("%s event has no invoice", event.Type)
+		}
+		return f(ctx, event, invoice)
+	})
+}
+
+// OnChargeCreated registers a handler of the "charge:created" events.
+func (handler *Handler) OnChargeCreated(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargeCreated, f)
+}
+
+// OnChargeConfirmed registers a handler of the "charge:confirmed" events.
+func (handler *Handler) OnChargeConfirmed(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargeConfirmed, f)
+}
+
+// OnChargeFailed registers a handler of the "charge:failed" events.
+func (handler *Handler) OnChargeFailed(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargeFailed, f)
+}
+
+// OnChargeDelayed registers a handler of the "charge:delayed" events.
+func (handler *Handler) OnChargeDelayed(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargeDelayed, f)
+}
+
+// OnChargePending registers a handler of the "charge:pending" events.
+func (handler *Handler) OnChargePending(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargePending, f)
+}
+
+// OnChargeResolved registers a handler of the "charge:resolved" events.
+func (handler *Handler) OnChargeResolved(f ChargeHandlerFunc) {
+	handler.OnCharge(coinbasecommerce.EventTypeChargeResolved, f)
+}
+
+// ServeHTTP handles a webhook request.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		handler.reject(w, r, http.StatusMethodNotAllowed, RejectionReasonMethodNotAllowed, errMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, handler.maxBodySize+1))
+	if err != nil {
+		handler.reject(w, r, http.StatusBadRequest, RejectionReasonUnreadableBody, err)
+		return
+	}
+	if int64(len(body)) > handler.maxBodySize {
+		handler.reject(w, r, http.StatusRequestEntityTooLarge, RejectionReasonBodyTooLarge, ErrBodyTooLarge)
+		return
+	}
+
+	if err := handler.verifier.VerifyHeader(body, r.Header); err != nil {
+		handler.reject(w, r, http.StatusBadRequest, RejectionReasonOf(err), err)
+		return
+	}
+	payload, err := ParsePayload(body)
+	if err != nil {
+		handler.reject(w, r, http.StatusBadRequest, RejectionReasonMalformedPayload, err)
+		return
+	}
+	event := payload.Event
+	if err := handler.verifier.VerifyEvent(event); err != nil {
+		handler.reject(w, r, http.StatusBadRequest, RejectionReasonOf(err), err)
+		return
+	}
+
+	err = handler.process(r.Context(), event)
+	switch {
+	case errors.Is(err, ErrEventProcessed):
+		handler.reject(w, r, http.StatusOK, RejectionReasonDuplicateEvent, err)
+	case errors.Is(err, ErrEventInProgress):
+		handler.reject(w, r, http.StatusConflict, RejectionReasonEventInProgress, err)
+	case err != nil:
+		if handler.logger != nil {
+			handler.logger.Error("webhook event handler failed",
+				"event_id", event.ID, "event_type", string(event.Type), "error", err)
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// process claims the event in the event store, if it's set, and passes it
+// to its handlers. The event is completed if the handlers succeed, and
+// released otherwise, even if one of them panics. It's released even if the
+// request was canceled, e.g. because the client disconnected, so that the
+// claim doesn't block the deliveries of the event that follow.
+func (handler *Handler) process(ctx context.Context, event coinbasecommerce.Event) error {
+	if handler.store == nil {
+		return handler.handle(ctx, event)
+	}
+
+	if err := handler.store.Claim(ctx, event.ID); err != nil {
+		return err
+	}
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+		if err := handler.store.Release(releaseCtx, event.ID); err != nil && handler.logger != nil {
+			handler.logger.Error("webhook event couldn't be released",
+				"event_id", event.ID, "event_type", string(event.Type), "error", err)
+		}
+	}()
+
+	if err := handler.handle(ctx, event); err != nil {
+		return err
+	}
+	if err := handler.store.Complete(ctx, event.ID); err != nil {
+		return fmt.Errorf("webhook event couldn't be completed: %w", err)
+	}
+	completed = true
+	return nil
+}
+
+// handle passes the event to the handlers of its type.
+func (handler *Handler) handle(ctx context.Context, event coinbasecommerce.Event) error {
+	handlers, ok := handler.handlers[event.Type]
+	if !ok {
+		handlers = handler.defaultHandlers
+	}
+	for _, f := range handlers {
+		if err := f(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reject responds to a rejected webhook request with the status code, and
+// reports the rejection. The duplicate deliveries are acknowledged, so they're
+// logged at debug level instead of warn level.
+func (handler *Handler) reject(
+	w http.ResponseWriter,
+	r *http.Request,
+	statusCode int,
+	reason RejectionReason,
+	err error,
+) {
+	if handler.logger != nil {
+		log := handler.logger.Warn
+		if statusCode == http.StatusOK {
+			log = handler.logger.Debug
+		}
+		log("webhook request rejected",
+			"status_code", statusCode, "reason", string(reason), "remote_addr", r.RemoteAddr, "error", err)
+	}
+	if handler.onRejection != nil {
+		handler.onRejection(r, reason, err)
+	}
+
+	if statusCode == http.StatusOK {
+		w.WriteHeader(statusCode)
+		return
+	}
+	http.Error(w, err.Error(), statusCode)
+}
@@ -0,0 +1,245 @@
+package webhooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest/factory"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+func TestHandlerRouting(t *testing.T) {
+	charge := factory.NewCompletedCharge()
+	tests := []struct {
+		name      string
+		event     coinbasecommerce.Event
+		wantCalls []string
+	}{
+		{"typed handlers", factory.NewEvent(coinbasecommerce.EventTypeChargeConfirmed, charge), []string{"confirmed", "on"}},
+		{"default handler", factory.NewEvent(coinbasecommerce.EventTypeChargeFailed, charge), []string{"default"}},
+		{"invoice handler", newInvoiceEvent(coinbasecommerce.EventTypeInvoicePaid), []string{"invoice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			handler := webhooks.NewHandler(webhooks.NewVerifier(testSecret))
+			handler.OnChargeConfirmed(func(ctx context.Context, event coinbasecommerce.Event, c coinbasecommerce.Charge) error {
+				if c.Code != charge.Code {
+					t.Errorf("charge code = %s, want %s", c.Code, charge.Code)
+				}
+				calls = append(calls, "confirmed")
+				return nil
+			})
+			handler.On(coinbasecommerce.EventTypeChargeConfirmed, func(ctx context.Context, event coinbasecommerce.Event) error {
+				calls = append(calls, "on")
+				return nil
+			})
+			handler.OnInvoice(coinbasecommerce.EventTypeInvoicePaid, func(ctx context.Context, event coinbasecommerce.Event, invoice coinbasecommerce.Invoice) error {
+				calls = append(calls, "invoice")
+				return nil
+			})
+			handler.OnDefault(func(ctx context.Context, event coinbasecommerce.Event) error {
+				calls = append(calls, "default")
+				return nil
+			})
+
+			response := post(handler, tt.event)
+			if response.Code != http.StatusOK {
+				t.Errorf("status code = %d, want %d", response.Code, http.StatusOK)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHandlerResponses(t *testing.T) {
+	event := factory.NewChargeEvent(factory.NewCompletedCharge())
+	payload, signature := factory.SignedWebhookPayload(event, testSecret)
+	maxBodySize := len(payload)
+	errHandler := errors.New("handler failed")
+
+	tests := []struct {
+		name           string
+		method         string
+		body           []byte
+		signature      string
+		handlerErr     error
+		wantStatusCode int
+		wantReason     webhooks.RejectionReason
+	}{
+		{"handled", http.MethodPost, payload, signature, nil, http.StatusOK, ""},
+		{"handler failed", http.MethodPost, payload, signature, errHandler, http.StatusInternalServerError, ""},
+		{"not POST", http.MethodGet, nil, "", nil, http.StatusMethodNotAllowed, webhooks.RejectionReasonMethodNotAllowed},
+		{"too large", http.MethodPost, bytes.Repeat([]byte(" "), maxBodySize+1), signature, nil, http.StatusRequestEntityTooLarge, webhooks.RejectionReasonBodyTooLarge},
+		{"no signature", http.MethodPost, payload, "", nil, http.StatusBadRequest, webhooks.RejectionReasonMissingSignature},
+		{"malformed signature", http.MethodPost, payload, "abc", nil, http.StatusBadRequest, webhooks.RejectionReasonMalformedSignature},
+		{"wrong signature", http.MethodPost, payload, webhooks.Sign(payload, "another-secret"), nil, http.StatusBadRequest, webhooks.RejectionReasonSignatureMismatch},
+		{"not a payload", http.MethodPost, []byte(`[]`), webhooks.Sign([]byte(`[]`), testSecret), nil, http.StatusBadRequest, webhooks.RejectionReasonMalformedPayload},
+		{"no event", http.MethodPost, []byte(`{"id":1}`), webhooks.Sign([]byte(`{"id":1}`), testSecret), nil, http.StatusBadRequest, webhooks.RejectionReasonMalformedPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reason webhooks.RejectionReason
+			handler := webhooks.NewHandler(webhooks.NewVerifier(testSecret),
+				webhooks.HandlerOptionMaxBodySize(int64(maxBodySize)),
+				webhooks.HandlerOptionOnRejection(func(r *http.Request, rejectionReason webhooks.RejectionReason, err error) {
+					reason = rejectionReason
+				}))
+			handler.OnDefault(func(ctx context.Context, event coinbasecommerce.Event) error {
+				return tt.handlerErr
+			})
+
+			request := httptest.NewRequest(tt.method, "/webhooks", bytes.NewReader(tt.body))
+			if tt.signature != "" {
+				request.Header.Set(webhooks.SignatureHeader, tt.signature)
+			}
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			if response.Code != tt.wantStatusCode {
+				t.Errorf("status code = %d, want %d", response.Code, tt.wantStatusCode)
+			}
+			if reason != tt.wantReason {
+				t.Errorf("rejection reason = %q, want %q", reason, tt.wantReason)
+			}
+			if tt.method != http.MethodPost && response.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow header = %q, want %q", response.Header().Get("Allow"), http.MethodPost)
+			}
+			if strings.Contains(response.Body.String(), errHandler.Error()) {
+				t.Errorf("response body %q contains the error of the handler", response.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerChargeTypeMismatch(t *testing.T) {
+	called := false
+	handler := webhooks.NewHandler(webhooks.NewVerifier(testSecret))
+	handler.OnCharge(coinbasecommerce.EventTypeInvoicePaid, func(ctx context.Context, event coinbasecommerce.Event, charge coinbasecommerce.Charge) error {
+		called = true
+		return nil
+	})
+
+	response := post(handler, newInvoiceEvent(coinbasecommerce.EventTypeInvoicePaid))
+	if response.Code != http.StatusInternalServerError {
+		t.Errorf("status code = %d, want %d", response.Code, http.StatusInternalServerError)
+	}
+	if called {
+		t.Error("charge handler was called with an invoice event")
+	}
+}
+
+func TestHandlerEventStore(t *testing.T) {
+	event := factory.NewChargeEvent(factory.NewCompletedCharge())
+	calls := 0
+	fail := true
+	var reason webhooks.RejectionReason
+	handler := webhooks.NewHandler(webhooks.NewVerifier(testSecret),
+		webhooks.HandlerOptionEventStore(webhooks.NewMemoryEventStore(time.Hour)),
+		webhooks.HandlerOptionOnRejection(func(r *http.Request, rejectionReason webhooks.RejectionReason, err error) {
+			reason = rejectionReason
+		}))
+	handler.OnDefault(func(ctx context.Context, event coinbasecommerce.Event) error {
+		calls++
+		if fail {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	deliveries := []struct {
+		name           string
+		fail           bool
+		wantStatusCode int
+		wantCalls      int
+		wantReason     webhooks.RejectionReason
+	}{
+		{"handler failed", true, http.StatusInternalServerError, 1, ""},
+		{"released event redelivered", false, http.StatusOK, 2, ""},
+		{"processed event redelivered", false, http.StatusOK, 2, webhooks.RejectionReasonDuplicateEvent},
+	}
+	for _, delivery := range deliveries {
+		fail, reason = delivery.fail, ""
+		response := post(handler, event)
+		if response.Code != delivery.wantStatusCode {
+			t.Errorf("%s: status code = %d, want %d", delivery.name, response.Code, delivery.wantStatusCode)
+		}
+		if calls != delivery.wantCalls {
+			t.Errorf("%s: calls = %d, want %d", delivery.name, calls, delivery.wantCalls)
+		}
+		if reason != delivery.wantReason {
+			t.Errorf("%s: rejection reason = %q, want %q", delivery.name, reason, delivery.wantReason)
+		}
+	}
+}
+
+func TestHandlerReleasesEventOfCanceledRequest(t *testing.T) {
+	event := factory.NewChargeEvent(factory.NewCompletedCharge())
+	store := &releaseRecordingStore{EventStore: webhooks.NewMemoryEventStore(time.Hour)}
+	handler := webhooks.NewHandler(webhooks.NewVerifier(testSecret), webhooks.HandlerOptionEventStore(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler.OnDefault(func(ctx context.Context, event coinbasecommerce.Event) error {
+		// the client disconnects while the event is handled
+		cancel()
+		return ctx.Err()
+	})
+	payload, signature := factory.SignedWebhookPayload(event, testSecret)
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(payload)).WithContext(ctx)
+	request.Header.Set(webhooks.SignatureHeader, signature)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	if store.releases != 1 {
+		t.Fatalf("releases = %d, want 1", store.releases)
+	}
+	if store.releaseErr != nil {
+		t.Errorf("Release() context error = %v, want nil", store.releaseErr)
+	}
+	if err := store.Claim(context.Background(), event.ID); err != nil {
+		t.Errorf("Claim() after release = %v, want nil", err)
+	}
+}
+
+// releaseRecordingStore is an EventStore that records whether the contexts
+// of the releases were done.
+type releaseRecordingStore struct {
+	webhooks.EventStore
+	releases   int
+	releaseErr error
+}
+
+func (store *releaseRecordingStore) Release(ctx context.Context, eventID string) error {
+	store.releases++
+	store.releaseErr = ctx.Err()
+	return store.EventStore.Release(ctx, eventID)
+}
+
+// post sends the signed webhook payload of the event to the handler.
+func post(handler http.Handler, event coinbasecommerce.Event) *httptest.ResponseRecorder {
+	payload, signature := factory.SignedWebhookPayload(event, testSecret)
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(payload))
+	request.Header.Set(webhooks.SignatureHeader, signature)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+// newInvoiceEvent creates an event of the type about an invoice.
+func newInvoiceEvent(eventType coinbasecommerce.EventType) coinbasecommerce.Event {
+	event := factory.NewEvent(eventType, factory.NewCharge())
+	event.Data = coinbasecommerce.Invoice{ID: "invoice-id"}
+	return event
+}
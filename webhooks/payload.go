@@ -0,0 +1,36 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// ErrMalformedPayload is returned when the body of a webhook request isn't
+// a webhook payload.
+var ErrMalformedPayload = errors.New("malformed webhook payload")
+
+// Payload is the body of a webhook request, which delivers an event.
+type Payload struct {
+	// ID identifies the delivery; it's not the ID of the event.
+	ID            int                    `json:"id"`
+	ScheduledFor  time.Time              `json:"scheduled_for"`
+	Event         coinbasecommerce.Event `json:"event"`
+	AttemptNumber int                    `json:"attempt_number"`
+}
+
+// ParsePayload decodes the body of a webhook request. The signature of the
+// body must be verified first.
+func ParsePayload(payload []byte) (Payload, error) {
+	var p Payload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Payload{}, fmt.Errorf("%w: %s", ErrMalformedPayload, err)
+	}
+	if p.Event.ID == "" || p.Event.Type == "" {
+		return Payload{}, fmt.Errorf("%w: the event has no ID or type", ErrMalformedPayload)
+	}
+	return p, nil
+}
@@ -0,0 +1,126 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+)
+
+// Replay errors
+var (
+	// ErrStaleEvent is returned when an event was created longer ago than the
+	// tolerance of the verifier.
+	ErrStaleEvent = errors.New("webhook event too old")
+	// ErrFutureEvent is returned when an event was created later than the
+	// tolerance of the verifier from now.
+	ErrFutureEvent = errors.New("webhook event from the future")
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// VerifierOptionFunc represents a function that can modify the contents of
+// the Verifier before it's used.
+type VerifierOptionFunc func(*Verifier)
+
+// VerifierOptionTolerance sets how far the creation time of an event can be
+// from the current time, so that a leaked signed payload can't be replayed
+// indefinitely. There's no tolerance by default.
+//
+// Coinbase Commerce delivers an event again for days until it's accepted,
+// and every delivery has the creation time of the event, so the tolerance
+// must be longer than that to not reject the retries. The event store of the
+// handler should remember the processed events for longer than the
+// tolerance, so that a replay within it is recognized as a duplicate.
+func VerifierOptionTolerance(tolerance time.Duration) VerifierOptionFunc {
+	if tolerance <= 0 {
+		panic(`invalid tolerance. valid values: tolerance > 0`)
+	}
+	return func(verifier *Verifier) {
+		verifier.tolerance = tolerance
+	}
+}
+
+// VerifierOptionClock sets the clock that the creation times of the events
+// are compared with. The system clock is used by default.
+func VerifierOptionClock(clock Clock) VerifierOptionFunc {
+	if clock == nil {
+		panic("clock cannot be equal to nil")
+	}
+	return func(verifier *Verifier) {
+		verifier.clock = clock
+	}
+}
+
+// VerifyEvent checks that the event was created within the tolerance of the
+// verifier from the current time. It always succeeds if the verifier has no
+// tolerance. The signature of the payload of the event must be verified
+// first.
+func (verifier *Verifier) VerifyEvent(event coinbasecommerce.Event) error {
+	if verifier.tolerance == 0 {
+		return nil
+	}
+
+	now := verifier.clock.Now()
+	if age := now.Sub(event.CreatedAt); age > verifier.tolerance {
+		return fmt.Errorf("%w: created %s ago", ErrStaleEvent, age.Round(time.Second))
+	} else if -age > verifier.tolerance {
+		return fmt.Errorf("%w: created %s from now", ErrFutureEvent, (-age).Round(time.Second))
+	}
+	return nil
+}
+
+// RejectionReason is why a webhook request was rejected, e.g. to count the
+// rejections by their reasons.
+type RejectionReason string
+
+// RejectionReason constants.
+const (
+	RejectionReasonMethodNotAllowed   RejectionReason = "method_not_allowed"
+	RejectionReasonUnreadableBody     RejectionReason = "unreadable_body"
+	RejectionReasonBodyTooLarge       RejectionReason = "body_too_large"
+	RejectionReasonMissingSignature   RejectionReason = "missing_signature"
+	RejectionReasonMalformedSignature RejectionReason = "malformed_signature"
+	RejectionReasonSignatureMismatch  RejectionReason = "signature_mismatch"
+	RejectionReasonMalformedPayload   RejectionReason = "malformed_payload"
+	RejectionReasonStaleEvent         RejectionReason = "stale_event"
+	RejectionReasonFutureEvent        RejectionReason = "future_event"
+	RejectionReasonDuplicateEvent     RejectionReason = "duplicate_event"
+	RejectionReasonEventInProgress    RejectionReason = "event_in_progress"
+)
+
+// RejectionReasonOf returns the reason of the rejection that the error of
+// the verifier, the event store or ParsePayload means, or an empty string if
+// the error isn't one of theirs.
+func RejectionReasonOf(err error) RejectionReason {
+	reasons := []struct {
+		err    error
+		reason RejectionReason
+	}{
+		{ErrBodyTooLarge, RejectionReasonBodyTooLarge},
+		{ErrMissingSignature, RejectionReasonMissingSignature},
+		{ErrMalformedSignature, RejectionReasonMalformedSignature},
+		{ErrSignatureMismatch, RejectionReasonSignatureMismatch},
+		{ErrMalformedPayload, RejectionReasonMalformedPayload},
+		{ErrStaleEvent, RejectionReasonStaleEvent},
+		{ErrFutureEvent, RejectionReasonFutureEvent},
+		{ErrEventProcessed, RejectionReasonDuplicateEvent},
+		{ErrEventInProgress, RejectionReasonEventInProgress},
+	}
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return ""
+}
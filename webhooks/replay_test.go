@@ -0,0 +1,110 @@
+package webhooks_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce"
+	"github.com/bmdelacruz/coinbasecommerce/cbctest/factory"
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+func TestVerifyEvent(t *testing.T) {
+	clock := newManualClock()
+	tests := []struct {
+		name      string
+		tolerance time.Duration
+		createdAt time.Time
+		wantErr   error
+	}{
+		{"now", time.Hour, clock.Now(), nil},
+		{"as old as the tolerance", time.Hour, clock.Now().Add(-time.Hour), nil},
+		{"older than the tolerance", time.Hour, clock.Now().Add(-time.Hour - time.Second), webhooks.ErrStaleEvent},
+		{"as far in the future as the tolerance", time.Hour, clock.Now().Add(time.Hour), nil},
+		{"further in the future than the tolerance", time.Hour, clock.Now().Add(time.Hour + time.Second), webhooks.ErrFutureEvent},
+		{"no tolerance", 0, clock.Now().Add(-365 * 24 * time.Hour), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optionFuncs := []webhooks.VerifierOptionFunc{webhooks.VerifierOptionClock(clock)}
+			if tt.tolerance != 0 {
+				optionFuncs = append(optionFuncs, webhooks.VerifierOptionTolerance(tt.tolerance))
+			}
+			verifier := webhooks.NewVerifierWithOptions([]string{testSecret}, optionFuncs...)
+
+			err := verifier.VerifyEvent(coinbasecommerce.Event{ID: "e1", CreatedAt: tt.createdAt})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyEvent() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRejectionReasonOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want webhooks.RejectionReason
+	}{
+		{webhooks.ErrBodyTooLarge, webhooks.RejectionReasonBodyTooLarge},
+		{webhooks.ErrMissingSignature, webhooks.RejectionReasonMissingSignature},
+		{fmt.Errorf("%w: too short", webhooks.ErrMalformedSignature), webhooks.RejectionReasonMalformedSignature},
+		{webhooks.ErrSignatureMismatch, webhooks.RejectionReasonSignatureMismatch},
+		{fmt.Errorf("%w: not JSON", webhooks.ErrMalformedPayload), webhooks.RejectionReasonMalformedPayload},
+		{fmt.Errorf("%w: created 2h ago", webhooks.ErrStaleEvent), webhooks.RejectionReasonStaleEvent},
+		{webhooks.ErrFutureEvent, webhooks.RejectionReasonFutureEvent},
+		{webhooks.ErrEventProcessed, webhooks.RejectionReasonDuplicateEvent},
+		{webhooks.ErrEventInProgress, webhooks.RejectionReasonEventInProgress},
+		{errors.New("handler failed"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := webhooks.RejectionReasonOf(tt.err); got != tt.want {
+			t.Errorf("RejectionReasonOf(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerRejectsReplays(t *testing.T) {
+	clock := newManualClock()
+	verifier := webhooks.NewVerifierWithOptions([]string{testSecret},
+		webhooks.VerifierOptionTolerance(time.Hour), webhooks.VerifierOptionClock(clock))
+	var reason webhooks.RejectionReason
+	handler := webhooks.NewHandler(verifier,
+		webhooks.HandlerOptionEventStore(webhooks.NewMemoryEventStore(2*time.Hour, webhooks.EventStoreOptionClock(clock))),
+		webhooks.HandlerOptionOnRejection(func(r *http.Request, rejectionReason webhooks.RejectionReason, err error) {
+			reason = rejectionReason
+		}))
+
+	event := factory.NewChargeEvent(factory.NewCompletedCharge())
+	event.CreatedAt = clock.Now()
+	futureEvent := factory.NewChargeEvent(factory.NewCompletedCharge())
+	futureEvent.CreatedAt = clock.Now().Add(3 * time.Hour)
+
+	deliveries := []struct {
+		name           string
+		event          coinbasecommerce.Event
+		after          time.Duration
+		wantStatusCode int
+		wantReason     webhooks.RejectionReason
+	}{
+		{"first delivery", event, 0, http.StatusOK, ""},
+		{"replay within the tolerance", event, 30 * time.Minute, http.StatusOK, webhooks.RejectionReasonDuplicateEvent},
+		{"replay after the tolerance", event, 31 * time.Minute, http.StatusBadRequest, webhooks.RejectionReasonStaleEvent},
+		{"event from the future", futureEvent, 0, http.StatusBadRequest, webhooks.RejectionReasonFutureEvent},
+	}
+	for _, delivery := range deliveries {
+		clock.advance(delivery.after)
+		reason = ""
+		response := post(handler, delivery.event)
+		if response.Code != delivery.wantStatusCode {
+			t.Errorf("%s: status code = %d, want %d", delivery.name, response.Code, delivery.wantStatusCode)
+		}
+		if reason != delivery.wantReason {
+			t.Errorf("%s: rejection reason = %q, want %q", delivery.name, reason, delivery.wantReason)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package webhooks helps receive the events that Coinbase Commerce sends to
+// the webhook subscriptions of an account.
+//
+// Every event is signed with the shared secret of the subscription, and the
+// signature must be verified against the raw body of the request before the
+// event is trusted:
+//
+//	verifier := webhooks.NewVerifier(cfg.WebhookSecrets...)
+//
+//	payload, err := ioutil.ReadAll(r.Body)
+//	...
+//	if err := verifier.Verify(payload, r.Header.Get(webhooks.SignatureHeader)); err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//
+// Handler does this for every request, and then passes the events to the
+// handlers of their types. A signed payload is valid forever, so to keep a
+// leaked one from being replayed, the verifier can reject the events that
+// are too old, and the handler can skip the events that it already
+// processed:
+//
+//	verifier := webhooks.NewVerifierWithOptions(cfg.WebhookSecrets,
+//		webhooks.VerifierOptionTolerance(72*time.Hour))
+//	handler := webhooks.NewHandler(verifier,
+//		webhooks.HandlerOptionEventStore(webhooks.NewMemoryEventStore(96*time.Hour)))
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the key of the header that contains the signature of
+// the body of a webhook request.
+const SignatureHeader = "X-CC-Webhook-Signature"
+
+// Signature errors
+var (
+	ErrMissingSignature   = errors.New("missing webhook signature")
+	ErrMalformedSignature = errors.New("malformed webhook signature")
+	ErrSignatureMismatch  = errors.New("webhook signature mismatch")
+)
+
+// Verifier verifies the signatures of the webhook requests. It accepts the
+// signatures that were made with any of its secrets, so that a secret can be
+// rotated without rejecting the events that are still signed with the old
+// one. It's safe for concurrent use by multiple goroutines.
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	clock     Clock
+}
+
+// NewVerifier creates a new verifier that accepts the signatures that were
+// made with any of the shared secrets.
+func NewVerifier(secrets ...string) *Verifier {
+	return NewVerifierWithOptions(secrets)
+}
+
+// NewVerifierWithOptions creates a new verifier like NewVerifier does, and
+// then applies the options to it.
+func NewVerifierWithOptions(secrets []string, optionFuncs ...VerifierOptionFunc) *Verifier {
+	if len(secrets) == 0 {
+		panic("secrets cannot be empty")
+	}
+
+	verifier := Verifier{
+		secrets:   make([][]byte, len(secrets)),
+		tolerance: 0,
+		clock:     systemClock{},
+	}
+	for i, secret := range secrets {
+		if secret == "" {
+			panic("secret cannot be empty")
+		}
+		verifier.secrets[i] = []byte(secret)
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&verifier)
+	}
+	return &verifier
+}
+
+// Verify checks that the signature, i.e. the value of the
+// `X-CC-Webhook-Signature` header, is the HMAC-SHA256 of the raw payload
+// with one of the secrets. The signatures are compared in constant time.
+func (verifier *Verifier) Verify(payload []byte, signature string) error {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	mac, err := hex.DecodeString(signature)
+	if err != nil || len(mac) != sha256.Size {
+		return fmt.Errorf("%w: expected %d hex-encoded bytes", ErrMalformedSignature, sha256.Size)
+	}
+
+	// every secret is tried so that the time doesn't tell which one matched
+	matched := false
+	for _, secret := range verifier.secrets {
+		if hmac.Equal(mac, computeMAC(payload, secret)) {
+			matched = true
+		}
+	}
+	if !matched {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// VerifyHeader checks the signature in the headers of a webhook request
+// like Verify does.
+func (verifier *Verifier) VerifyHeader(payload []byte, header http.Header) error {
+	return verifier.Verify(payload, header.Get(SignatureHeader))
+}
+
+// Sign computes the signature of the payload with the secret, e.g. to send
+// a signed event to a webhook in a test.
+func Sign(payload []byte, secret string) string {
+	return hex.EncodeToString(computeMAC(payload, []byte(secret)))
+}
+
+func computeMAC(payload, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,74 @@
+package webhooks_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+const (
+	testSecret    = "shared-secret"
+	testOldSecret = "old-shared-secret"
+	testPayload   = `{"id":1,"event":{"id":"e1","type":"charge:created"}}`
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(testPayload)
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   error
+	}{
+		{"signed with the secret", webhooks.Sign(payload, testSecret), nil},
+		{"signed with the other secret", webhooks.Sign(payload, testOldSecret), nil},
+		{"surrounded by spaces", " " + webhooks.Sign(payload, testSecret) + "\n", nil},
+		{"missing", "", webhooks.ErrMissingSignature},
+		{"blank", "   ", webhooks.ErrMissingSignature},
+		{"not hex", strings.Repeat("z", 64), webhooks.ErrMalformedSignature},
+		{"too short", webhooks.Sign(payload, testSecret)[:62], webhooks.ErrMalformedSignature},
+		{"signed with another secret", webhooks.Sign(payload, "another-secret"), webhooks.ErrSignatureMismatch},
+		{"signed another payload", webhooks.Sign([]byte(testPayload+" "), testSecret), webhooks.ErrSignatureMismatch},
+	}
+
+	verifier := webhooks.NewVerifier(testSecret, testOldSecret)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(payload, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+
+			header := http.Header{}
+			if tt.signature != "" {
+				header.Set(webhooks.SignatureHeader, tt.signature)
+			}
+			err = verifier.VerifyHeader(payload, header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyHeader() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVerifierPanics(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+	}{
+		{"no secrets", nil},
+		{"empty secret", []string{testSecret, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewVerifier(%q) didn't panic", tt.secrets)
+				}
+			}()
+			webhooks.NewVerifier(tt.secrets...)
+		})
+	}
+}
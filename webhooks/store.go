@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors of the event stores.
+var (
+	// ErrEventProcessed is returned when an event was already processed.
+	ErrEventProcessed = errors.New("event already processed")
+	// ErrEventInProgress is returned when an event is being processed by
+	// another delivery of it.
+	ErrEventInProgress = errors.New("event in progress")
+)
+
+// EventStore records the IDs of the events that were processed, so that an
+// event that is delivered more than once is only processed once.
+//
+// An event is claimed before it's processed. Then, it's either completed if
+// it was processed, or released if it wasn't, so that it can be claimed
+// again when it's delivered again.
+type EventStore interface {
+	// Claim claims the event with the ID for processing. It returns
+	// ErrEventProcessed if the event was completed, and ErrEventInProgress if
+	// it's claimed by another delivery of it.
+	Claim(ctx context.Context, eventID string) error
+	// Complete records that the claimed event with the ID was processed.
+	Complete(ctx context.Context, eventID string) error
+	// Release gives up the claim of the event with the ID, which wasn't
+	// processed.
+	Release(ctx context.Context, eventID string) error
+}
+
+// MemoryEventStore is an EventStore that keeps the IDs of the processed
+// events in memory until their TTL passes. It's safe for concurrent use by
+// multiple goroutines.
+type MemoryEventStore struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu         sync.Mutex
+	inProgress map[string]bool
+	processed  map[string]time.Time
+	// expirations are the processed events in the order that they expire.
+	expirations []eventExpiration
+}
+
+type eventExpiration struct {
+	eventID   string
+	expiresAt time.Time
+}
+
+// EventStoreOptionFunc represents a function that can modify the contents of
+// an in-memory or file-backed event store before it's used.
+type EventStoreOptionFunc func(*MemoryEventStore)
+
+// EventStoreOptionClock sets the clock that the TTLs of the processed events
+// are measured with. The system clock is used by default.
+func EventStoreOptionClock(clock Clock) EventStoreOptionFunc {
+	if clock == nil {
+		panic("clock cannot be equal to nil")
+	}
+	return func(store *MemoryEventStore) {
+		store.clock = clock
+	}
+}
+
+// NewMemoryEventStore creates a new in-memory event store that forgets a
+// processed event once the TTL has passed since it was completed. The TTL
+// should be longer than the period in which an event can be delivered again,
+// and than the tolerance of the verifier if it has one.
+func NewMemoryEventStore(ttl time.Duration, optionFuncs ...EventStoreOptionFunc) *MemoryEventStore {
+	if ttl <= 0 {
+		panic(`invalid ttl. valid values: ttl > 0`)
+	}
+
+	store := MemoryEventStore{
+		ttl:        ttl,
+		clock:      systemClock{},
+		inProgress: make(map[string]bool),
+		processed:  make(map[string]time.Time),
+	}
+	for _, optionFunc := range optionFuncs {
+		optionFunc(&store)
+	}
+	return &store
+}
+
+// Claim claims the event with the ID for processing.
+func (store *MemoryEventStore) Claim(ctx context.Context, eventID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune()
+	if _, ok := store.processed[eventID]; ok {
+		return ErrEventProcessed
+	}
+	if store.inProgress[eventID] {
+		return ErrEventInProgress
+	}
+	store.inProgress[eventID] = true
+	return nil
+}
+
+// Complete records that the claimed event with the ID was processed.
+func (store *MemoryEventStore) Complete(ctx context.Context, eventID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.inProgress, eventID)
+	store.add(eventID, store.clock.Now())
+	return nil
+}
+
+// Release gives up the claim of the event with the ID.
+func (store *MemoryEventStore) Release(ctx context.Context, eventID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.inProgress, eventID)
+	return nil
+}
+
+// add records that the event with the ID was processed at the time. The
+// caller must hold the lock of the store.
+func (store *MemoryEventStore) add(eventID string, processedAt time.Time) {
+	expiresAt := processedAt.Add(store.ttl)
+	store.processed[eventID] = expiresAt
+	store.expirations = append(store.expirations, eventExpiration{eventID: eventID, expiresAt: expiresAt})
+}
+
+// prune forgets the processed events whose TTL has passed. The caller must
+// hold the lock of the store.
+func (store *MemoryEventStore) prune() {
+	now := store.clock.Now()
+	for len(store.expirations) > 0 && !now.Before(store.expirations[0].expiresAt) {
+		expiration := store.expirations[0]
+		if store.processed[expiration.eventID].Equal(expiration.expiresAt) {
+			delete(store.processed, expiration.eventID)
+		}
+		store.expirations = store.expirations[1:]
+	}
+}
+
+// size returns the number of processed events that the store remembers.
+func (store *MemoryEventStore) size() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune()
+	return len(store.processed)
+}
+
+// records returns the records of the processed events that the store
+// remembers, in the order that they were completed.
+func (store *MemoryEventStore) records() []eventRecord {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune()
+	records := make([]eventRecord, 0, len(store.processed))
+	for _, expiration := range store.expirations {
+		if store.processed[expiration.eventID].Equal(expiration.expiresAt) {
+			records = append(records, eventRecord{
+				ID:          expiration.eventID,
+				ProcessedAt: expiration.expiresAt.Add(-store.ttl),
+			})
+		}
+	}
+	return records
+}
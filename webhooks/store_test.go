@@ -0,0 +1,235 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/coinbasecommerce/webhooks"
+)
+
+func TestMemoryEventStore(t *testing.T) {
+	clock := newManualClock()
+	store := webhooks.NewMemoryEventStore(time.Hour, webhooks.EventStoreOptionClock(clock))
+	testEventStore(t, store, clock)
+}
+
+func TestFileEventStore(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	clock := newManualClock()
+	store, err := webhooks.NewFileEventStore(path, time.Hour, webhooks.EventStoreOptionClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testEventStore(t, store, clock)
+}
+
+// testEventStore checks that the store, whose TTL is an hour, claims,
+// completes, releases and forgets the events.
+func testEventStore(t *testing.T, store webhooks.EventStore, clock *manualClock) {
+	ctx := context.Background()
+	steps := []struct {
+		name    string
+		do      func() error
+		wantErr error
+	}{
+		{"claim", func() error { return store.Claim(ctx, "e1") }, nil},
+		{"claim while in progress", func() error { return store.Claim(ctx, "e1") }, webhooks.ErrEventInProgress},
+		{"claim another event", func() error { return store.Claim(ctx, "e2") }, nil},
+		{"release", func() error { return store.Release(ctx, "e2") }, nil},
+		{"claim after release", func() error { return store.Claim(ctx, "e2") }, nil},
+		{"complete", func() error { return store.Complete(ctx, "e1") }, nil},
+		{"claim after complete", func() error { return store.Claim(ctx, "e1") }, webhooks.ErrEventProcessed},
+		{"claim before the TTL passes", func() error {
+			clock.advance(59 * time.Minute)
+			return store.Claim(ctx, "e1")
+		}, webhooks.ErrEventProcessed},
+		{"claim after the TTL passes", func() error {
+			clock.advance(time.Minute)
+			return store.Claim(ctx, "e1")
+		}, nil},
+	}
+	for _, step := range steps {
+		if err := step.do(); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+}
+
+func TestFileEventStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	clock := newManualClock()
+	store, err := webhooks.NewFileEventStore(path, time.Hour, webhooks.EventStoreOptionClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eventID := range []string{"e1", "e2"} {
+		if err := store.Claim(ctx, eventID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Complete(ctx, eventID); err != nil {
+			t.Fatal(err)
+		}
+		clock.advance(30 * time.Minute)
+	}
+	if err := store.Claim(ctx, "e3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Complete(ctx, "e3"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Complete() after Close() = %v, want %v", err, os.ErrClosed)
+	}
+
+	// e1 expires as the store reopens, so it's compacted away
+	clock.advance(time.Minute)
+	store, err = webhooks.NewFileEventStore(path, time.Hour, webhooks.EventStoreOptionClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), `"e2"`) {
+		t.Errorf("compacted file = %q, want only the record of e2", data)
+	}
+	tests := []struct {
+		eventID string
+		wantErr error
+	}{
+		{"e1", nil},
+		{"e2", webhooks.ErrEventProcessed},
+		{"e3", nil},
+	}
+	for _, tt := range tests {
+		if err := store.Claim(ctx, tt.eventID); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Claim(%s) after reopening = %v, want %v", tt.eventID, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFileEventStoreCompaction(t *testing.T) {
+	ctx := context.Background()
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	clock := newManualClock()
+	store, err := webhooks.NewFileEventStore(path, time.Hour, webhooks.EventStoreOptionClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	complete := func(eventID string) {
+		t.Helper()
+		if err := store.Claim(ctx, eventID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Complete(ctx, eventID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the 1024th record is written after the others expire, so the file is
+	// compacted to it
+	for i := 0; i < 1023; i++ {
+		complete(fmt.Sprintf("expired-%d", i))
+	}
+	clock.advance(time.Hour)
+	complete("compacted")
+	// the records after the compaction must be appended to the file at the
+	// path, rather than to the file that it replaced
+	complete("after-compaction")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 ||
+		!strings.Contains(string(data), `"compacted"`) || !strings.Contains(string(data), `"after-compaction"`) {
+		t.Errorf("compacted file = %q, want only the records after the others expired", data)
+	}
+	matches, err := filepath.Glob(path + ".*.tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("temporary files = %v, want none", matches)
+	}
+}
+
+func TestFileEventStoreCorruptFile(t *testing.T) {
+	now := newManualClock().Now().Format(time.RFC3339)
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"truncated last record", `{"id":"e1","processed_at":"` + now + `"}` + "\n" + `{"id":"e2","proc`, false},
+		{"invalid record", `{"id":"e1"` + "\n" + `{"id":"e2","processed_at":"` + now + `"}` + "\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := tempStorePath(t)
+			defer cleanup()
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := webhooks.NewFileEventStore(path, time.Hour, webhooks.EventStoreOptionClock(newManualClock()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFileEventStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer store.Close()
+			if err := store.Claim(context.Background(), "e1"); !errors.Is(err, webhooks.ErrEventProcessed) {
+				t.Errorf("Claim(e1) = %v, want %v", err, webhooks.ErrEventProcessed)
+			}
+		})
+	}
+}
+
+// manualClock is a Clock whose time only changes when it's advanced.
+type manualClock struct {
+	now time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (clock *manualClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *manualClock) advance(d time.Duration) {
+	clock.now = clock.now.Add(d)
+}
+
+// tempStorePath returns the path of an event store file in a new temporary
+// directory, and a function that removes the directory.
+func tempStorePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "events.jsonl"), func() { os.RemoveAll(dir) }
+}